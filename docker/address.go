@@ -0,0 +1,297 @@
+package docker
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Labels that let a container override the target Newt computes for it. They take
+// precedence over the configured AddressMode, which in turn overrides the bridge
+// network heuristic.
+const (
+	TargetAddressLabel = "newt.target.address"
+	TargetPortLabel    = "newt.target.port"
+)
+
+// TargetTTLLabel limits how long an ephemeral container (e.g. a CI runner) is
+// advertised as a target after it started, as a Go duration such as "30m"
+const TargetTTLLabel = "newt.target.ttl"
+
+// AddressMode selects whether container IP addresses or hostnames are sent to Pangolin
+type AddressMode string
+
+const (
+	// AddressModeAuto uses IP addresses when Newt is only attached to the default
+	// bridge network, where Docker provides no name resolution, and hostnames
+	// otherwise. This is the default.
+	AddressModeAuto AddressMode = "auto"
+	// AddressModeIP always sends container IP addresses. Pangolin targets then
+	// point at the container IP, which changes when the container is recreated.
+	AddressModeIP AddressMode = "ip"
+	// AddressModeHostname always sends hostnames. Pangolin targets then rely on
+	// Docker DNS, so Newt must share a user defined network with the target.
+	AddressModeHostname AddressMode = "hostname"
+)
+
+// ParseAddressMode maps "auto", "ip" or "hostname" to an AddressMode. An empty
+// string selects AddressModeAuto.
+func ParseAddressMode(mode string) (AddressMode, error) {
+	switch AddressMode(strings.ToLower(strings.TrimSpace(mode))) {
+	case "", AddressModeAuto:
+		return AddressModeAuto, nil
+	case AddressModeIP:
+		return AddressModeIP, nil
+	case AddressModeHostname:
+		return AddressModeHostname, nil
+	default:
+		return AddressModeAuto, fmt.Errorf("unknown address mode: %q (expected auto, ip or hostname)", mode)
+	}
+}
+
+// useIPAddresses applies the mode to the result of the bridge network heuristic
+func (m AddressMode) useIPAddresses(heuristic bool) bool {
+	switch m {
+	case AddressModeIP:
+		return true
+	case AddressModeHostname:
+		return false
+	default:
+		return heuristic
+	}
+}
+
+// AddressFamily selects which IP addresses are used when container IP addresses
+// are sent to Pangolin (see AddressMode)
+type AddressFamily string
+
+const (
+	// AddressFamilyIPv4 only uses IPv4 addresses. This is the default.
+	AddressFamilyIPv4 AddressFamily = "ipv4"
+	// AddressFamilyIPv6 only uses global IPv6 addresses, for IPv6-only networks
+	AddressFamilyIPv6 AddressFamily = "ipv6"
+	// AddressFamilyDualStack prefers IPv4 addresses and falls back to global IPv6 addresses
+	AddressFamilyDualStack AddressFamily = "dualstack"
+)
+
+// ParseAddressFamily maps "ipv4", "ipv6" or "dualstack" to an AddressFamily. An
+// empty string selects AddressFamilyIPv4.
+func ParseAddressFamily(family string) (AddressFamily, error) {
+	switch AddressFamily(strings.ToLower(strings.TrimSpace(family))) {
+	case "", AddressFamilyIPv4:
+		return AddressFamilyIPv4, nil
+	case AddressFamilyIPv6:
+		return AddressFamilyIPv6, nil
+	case AddressFamilyDualStack:
+		return AddressFamilyDualStack, nil
+	default:
+		return AddressFamilyIPv4, fmt.Errorf("unknown address family: %q (expected ipv4, ipv6 or dualstack)", family)
+	}
+}
+
+// kinds returns the address kinds the family selects, in order of preference
+func (f AddressFamily) kinds() []AddressKind {
+	switch f {
+	case AddressFamilyIPv6:
+		return []AddressKind{AddressKindIPv6}
+	case AddressFamilyDualStack:
+		return []AddressKind{AddressKindIPv4, AddressKindIPv6}
+	default:
+		return []AddressKind{AddressKindIPv4}
+	}
+}
+
+// ipAddressOfFamily returns the first address of the family across the networks,
+// walked in name order with user defined networks first when preferUserNetworks
+// is set, or an error naming the family when the container has none
+func ipAddressOfFamily(networks map[string]Network, family AddressFamily, preferUserNetworks bool) (string, error) {
+	c := Container{Networks: networks}
+	names := sortedNetworkNames(c)
+	if preferUserNetworks {
+		names = preferredNetworkNames(c)
+	}
+	for _, kind := range family.kinds() {
+		if address := addressOfKindIn(c, kind, names); address != "" {
+			return address, nil
+		}
+	}
+	if family == "" {
+		family = AddressFamilyIPv4
+	}
+	return "", fmt.Errorf("%w: no %s address on any network", ErrNoAddressOfFamily, family)
+}
+
+// AddressKind is a kind of address a container can be reached at
+type AddressKind string
+
+const (
+	AddressKindHostname AddressKind = "hostname" // container hostname, or its name when unset
+	AddressKindAlias    AddressKind = "alias"    // first network alias or DNS name
+	AddressKindIPv4     AddressKind = "ipv4"     // first network IPv4 address
+	AddressKindIPv6     AddressKind = "ipv6"     // first network global IPv6 address
+)
+
+// DefaultAddressPreference is used when a resolver has no explicit preference
+var DefaultAddressPreference = []AddressKind{AddressKindHostname, AddressKindAlias, AddressKindIPv4, AddressKindIPv6}
+
+// AddressResolver decides which address a Pangolin target should point at for a
+// container. The zero value honors the address selected during discovery
+// (Container.TargetAddress, see AddressMode) and otherwise falls back to
+// DefaultAddressPreference.
+type AddressResolver struct {
+	// Preference is the order in which address kinds are tried. When set it
+	// takes precedence over the address selected during discovery.
+	Preference []AddressKind
+}
+
+// ResolveTargetAddress returns the address a Pangolin target should point at
+// for the container using the default AddressResolver
+func ResolveTargetAddress(c Container) (string, error) {
+	return AddressResolver{}.ResolveTargetAddress(c)
+}
+
+// ResolveTargetAddress returns the address a Pangolin target should point at for
+// the container, or an error if none of the preferred address kinds is available.
+// A valid TargetAddressLabel always wins over the preference.
+func (r AddressResolver) ResolveTargetAddress(c Container) (string, error) {
+	address, _, err := r.resolveTargetAddress(c)
+	return address, err
+}
+
+// resolveTargetAddress implements ResolveTargetAddress and also returns why the address was chosen
+func (r AddressResolver) resolveTargetAddress(c Container) (string, string, error) {
+	if address, err := labelTargetAddress(c.Labels); err == nil && address != "" {
+		return address, TargetReasonLabel, nil
+	}
+	if _, ok := c.Labels[TargetNameLabel]; ok && c.TargetReason == TargetReasonLabel && c.TargetAddress != "" {
+		// Rendered during discovery, falling back to the container name on errors
+		return c.TargetAddress, TargetReasonLabel, nil
+	}
+
+	preference := r.Preference
+	if len(preference) == 0 {
+		if c.TargetAddress != "" {
+			reason := c.TargetReason
+			if reason == "" {
+				reason = TargetReasonHeuristic
+			}
+			return c.TargetAddress, reason, nil
+		}
+		preference = DefaultAddressPreference
+	}
+
+	for _, kind := range preference {
+		if address := addressOfKind(c, kind); address != "" {
+			return address, TargetReasonPreference + ":" + string(kind), nil
+		}
+	}
+
+	return "", "", fmt.Errorf("no usable address for container %s (tried %v)", c.Name, preference)
+}
+
+// addressOfKind returns the container's address of the given kind or an empty string.
+// Networks are walked in name order so the result is stable.
+func addressOfKind(c Container, kind AddressKind) string {
+	return addressOfKindIn(c, kind, sortedNetworkNames(c))
+}
+
+// addressOfKindIn is addressOfKind walking the networks in the given order
+func addressOfKindIn(c Container, kind AddressKind, names []string) string {
+	switch kind {
+	case AddressKindHostname:
+		if c.Hostname != "" {
+			return c.Hostname
+		}
+		return c.Name
+	case AddressKindAlias:
+		for _, name := range names {
+			network := c.Networks[name]
+			if len(network.Aliases) > 0 {
+				return network.Aliases[0]
+			}
+			if len(network.DNSNames) > 0 {
+				return network.DNSNames[0]
+			}
+		}
+	case AddressKindIPv4:
+		for _, name := range names {
+			if ip := c.Networks[name].IPAddress; ip != "" {
+				return ip
+			}
+		}
+	case AddressKindIPv6:
+		for _, name := range names {
+			if ip := c.Networks[name].GlobalIPv6Address; ip != "" {
+				return ip
+			}
+		}
+	}
+	return ""
+}
+
+// labelTargetAddress returns the address set by TargetAddressLabel. It returns an
+// empty string when the label is unset and an error when it is not a valid IP
+// address or hostname.
+func labelTargetAddress(labels map[string]string) (string, error) {
+	value, ok := labels[TargetAddressLabel]
+	if !ok {
+		return "", nil
+	}
+
+	address := strings.TrimSpace(value)
+	if net.ParseIP(address) != nil || validHostname(address) {
+		return address, nil
+	}
+	return "", fmt.Errorf("invalid %s label %q: expected an IP address or hostname", TargetAddressLabel, value)
+}
+
+// labelTargetPort returns the port set by TargetPortLabel. It returns 0 when the
+// label is unset and an error when it is not a port between 1 and 65535.
+func labelTargetPort(labels map[string]string) (int, error) {
+	value, ok := labels[TargetPortLabel]
+	if !ok {
+		return 0, nil
+	}
+
+	port, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || port < 1 || port > 65535 {
+		return 0, fmt.Errorf("invalid %s label %q: expected a port between 1 and 65535", TargetPortLabel, value)
+	}
+	return port, nil
+}
+
+// validHostname reports whether name is a syntactically valid DNS hostname.
+// Underscores are accepted as Docker allows them in container names.
+func validHostname(name string) bool {
+	if name == "" || len(name) > 253 {
+		return false
+	}
+	for _, part := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		if part == "" || len(part) > 63 || part[0] == '-' || part[len(part)-1] == '-' {
+			return false
+		}
+		for _, r := range part {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// labelTargetTTL returns the duration set by TargetTTLLabel. It returns 0 when the
+// label is unset and an error when it is not a positive duration.
+func labelTargetTTL(labels map[string]string) (time.Duration, error) {
+	value, ok := labels[TargetTTLLabel]
+	if !ok {
+		return 0, nil
+	}
+
+	ttl, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil || ttl <= 0 {
+		return 0, fmt.Errorf("invalid %s label %q: expected a positive duration such as 30m", TargetTTLLabel, value)
+	}
+	return ttl, nil
+}
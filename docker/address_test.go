@@ -0,0 +1,109 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+func TestIPAddressOfFamilyMissing(t *testing.T) {
+	networks := map[string]Network{"shop": {IPAddress: "172.20.0.5"}}
+
+	if _, err := ipAddressOfFamily(networks, AddressFamilyIPv6, false); !errors.Is(err, ErrNoAddressOfFamily) {
+		t.Errorf("ipAddressOfFamily(ipv6) error = %v, want ErrNoAddressOfFamily", err)
+	}
+	if ip, err := ipAddressOfFamily(networks, AddressFamilyDualStack, false); err != nil || ip != "172.20.0.5" {
+		t.Errorf("ipAddressOfFamily(dualstack) = %q, %v, want 172.20.0.5", ip, err)
+	}
+}
+
+func TestListContainersRecordsMissingAddressFamily(t *testing.T) {
+	api := &fakeAPI{summaries: []container.Summary{fakeContainer("0123456789abcdef", "web", "shop", "172.20.0.5")}}
+	d := newFakeClient(t, api)
+
+	containers, err := d.ListContainers(context.Background(), false, ListOptions{AddressMode: AddressModeIP, AddressFamily: AddressFamilyIPv6})
+	if err != nil || len(containers) != 1 {
+		t.Fatalf("ListContainers = %v, %v, want one container", containers, err)
+	}
+	c := containers[0]
+	if !strings.Contains(c.AddressError, ErrNoAddressOfFamily.Error()) {
+		t.Errorf("AddressError = %q, want it to mention %q", c.AddressError, ErrNoAddressOfFamily)
+	}
+	if c.TargetAddress != "0123456789ab" {
+		t.Errorf("TargetAddress = %q, want the hostname", c.TargetAddress)
+	}
+
+	containers, err = d.ListContainers(context.Background(), false, ListOptions{AddressMode: AddressModeIP})
+	if err != nil || len(containers) != 1 || containers[0].AddressError != "" || containers[0].TargetAddress != "172.20.0.5" {
+		t.Errorf("IPv4 listing = %+v, %v, want the IPv4 address without AddressError", containers, err)
+	}
+}
+
+func TestLabelTargetAddress(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"10.0.0.5", "10.0.0.5", false},
+		{" 10.0.0.5 ", "10.0.0.5", false},
+		{"fd00::5", "fd00::5", false},
+		{"web.internal", "web.internal", false},
+		{"my_app", "my_app", false},
+		{"", "", true},
+		{"   ", "", true},
+		{"10.0.0.5:80", "", true},
+		{"http://web", "", true},
+		{"-web", "", true},
+		{"web..internal", "", true},
+		{"web internal", "", true},
+		{strings.Repeat("a", 64), "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := labelTargetAddress(map[string]string{TargetAddressLabel: tt.value})
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("labelTargetAddress(%q) = %q, %v, want %q, error %t", tt.value, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+
+	if got, err := labelTargetAddress(map[string]string{}); got != "" || err != nil {
+		t.Errorf("labelTargetAddress without label = %q, %v, want no address and no error", got, err)
+	}
+}
+
+func TestLabelTargetPort(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{"8080", 8080, false},
+		{" 443 ", 443, false},
+		{"1", 1, false},
+		{"65535", 65535, false},
+		{"", 0, true},
+		{"0", 0, true},
+		{"65536", 0, true},
+		{"-80", 0, true},
+		{"80/tcp", 0, true},
+		{"http", 0, true},
+		{"8080.5", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := labelTargetPort(map[string]string{TargetPortLabel: tt.value})
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("labelTargetPort(%q) = %d, %v, want %d, error %t", tt.value, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+
+	if got, err := labelTargetPort(map[string]string{}); got != 0 || err != nil {
+		t.Errorf("labelTargetPort without label = %d, %v, want 0 and no error", got, err)
+	}
+}
@@ -0,0 +1,39 @@
+package docker
+
+import (
+	"context"
+	"io"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/docker/docker/api/types/system"
+	"github.com/docker/docker/client"
+)
+
+// API is the part of the Docker client a Client uses. *client.Client implements
+// it and is used by NewClient; NewClientFromAPI accepts any other implementation,
+// e.g. a fake daemon so listing and validation can run without Docker.
+type API interface {
+	ContainerList(ctx context.Context, options container.ListOptions) ([]container.Summary, error)
+	ContainerInspect(ctx context.Context, containerID string) (container.InspectResponse, error)
+	ContainerLogs(ctx context.Context, containerID string, options container.LogsOptions) (io.ReadCloser, error)
+	NetworkInspect(ctx context.Context, networkID string, options network.InspectOptions) (network.Inspect, error)
+	Events(ctx context.Context, options events.ListOptions) (<-chan events.Message, <-chan error)
+	ServiceList(ctx context.Context, options swarm.ServiceListOptions) ([]swarm.Service, error)
+	Info(ctx context.Context) (system.Info, error)
+	ServerVersion(ctx context.Context) (types.Version, error)
+	ClientVersion() string
+	Close() error
+}
+
+var _ API = (*client.Client)(nil)
+
+// NewClientFromAPI creates a Client that talks to the daemon through api. The
+// socket path is only used to identify the daemon, e.g. in logs, the cache and
+// Container.SourceSocket.
+func NewClientFromAPI(socketPath string, api API) *Client {
+	return &Client{socketPath: socketPath, host: normalizeDockerHost(socketPath), cli: api}
+}
@@ -0,0 +1,374 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/docker/docker/api/types/system"
+)
+
+// fakeAPI is an in-memory daemon for tests. Listed containers are inspected from
+// their summary unless inspects or inspectErr override it; unknown containers and
+// networks are not found.
+type fakeAPI struct {
+	mu         sync.Mutex
+	summaries  []container.Summary
+	inspects   map[string]container.InspectResponse
+	networks   map[string]network.Inspect
+	inspectErr map[string]error
+	listErr    error
+	listCalls  int
+}
+
+var _ API = (*fakeAPI)(nil)
+
+func (f *fakeAPI) ContainerList(ctx context.Context, options container.ListOptions) ([]container.Summary, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.listCalls++
+	if f.listErr != nil {
+		return nil, f.listErr
+	}
+	// Like a real daemon, every response is decoded into fresh maps
+	var summaries []container.Summary
+	for _, c := range f.summaries {
+		if !onFilteredNetwork(c, options.Filters.Get("network")) {
+			continue
+		}
+		c.Labels = maps.Clone(c.Labels)
+		summaries = append(summaries, c)
+	}
+	return summaries, nil
+}
+
+// onFilteredNetwork applies the daemon's network filter, which matches a network
+// name or ID and ORs multiple values
+func onFilteredNetwork(c container.Summary, networks []string) bool {
+	if len(networks) == 0 {
+		return true
+	}
+	if c.NetworkSettings == nil {
+		return false
+	}
+	for name, endpoint := range c.NetworkSettings.Networks {
+		if slices.Contains(networks, name) || slices.Contains(networks, endpoint.NetworkID) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *fakeAPI) ContainerInspect(ctx context.Context, containerID string) (container.InspectResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err, ok := f.inspectErr[containerID]; ok {
+		return container.InspectResponse{}, err
+	}
+	if info, ok := f.inspects[containerID]; ok {
+		return info, nil
+	}
+	for _, c := range f.summaries {
+		if c.ID == containerID {
+			return container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{ID: c.ID, Name: c.Names[0], State: &container.State{Status: c.State}},
+				Config:            &container.Config{Hostname: shortID(c.ID), Labels: maps.Clone(c.Labels)},
+			}, nil
+		}
+	}
+	return container.InspectResponse{}, fmt.Errorf("no such container %s: %w", containerID, cerrdefs.ErrNotFound)
+}
+
+func (f *fakeAPI) ContainerLogs(ctx context.Context, containerID string, options container.LogsOptions) (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader("")), nil
+}
+
+func (f *fakeAPI) NetworkInspect(ctx context.Context, networkID string, options network.InspectOptions) (network.Inspect, error) {
+	if n, ok := f.networks[networkID]; ok {
+		return n, nil
+	}
+	return network.Inspect{}, fmt.Errorf("no such network %s: %w", networkID, cerrdefs.ErrNotFound)
+}
+
+func (f *fakeAPI) Events(ctx context.Context, options events.ListOptions) (<-chan events.Message, <-chan error) {
+	return make(chan events.Message), make(chan error)
+}
+
+func (f *fakeAPI) ServiceList(ctx context.Context, options swarm.ServiceListOptions) ([]swarm.Service, error) {
+	return nil, nil
+}
+
+func (f *fakeAPI) Info(ctx context.Context) (system.Info, error) {
+	return system.Info{}, nil
+}
+
+func (f *fakeAPI) ServerVersion(ctx context.Context) (types.Version, error) {
+	return types.Version{Version: "28.3.3", APIVersion: "1.51"}, nil
+}
+
+func (f *fakeAPI) ClientVersion() string { return "1.51" }
+
+func (f *fakeAPI) Close() error { return nil }
+
+// startedAt sets the start time the fake reports when the container is inspected
+func (f *fakeAPI) startedAt(c container.Summary, started time.Time) {
+	if f.inspects == nil {
+		f.inspects = make(map[string]container.InspectResponse)
+	}
+	f.inspects[c.ID] = container.InspectResponse{
+		ContainerJSONBase: &container.ContainerJSONBase{ID: c.ID, Name: c.Names[0], State: &container.State{Status: c.State, StartedAt: started.Format(time.RFC3339Nano)}},
+		Config:            &container.Config{Hostname: shortID(c.ID), Labels: c.Labels},
+	}
+}
+
+// fakeContainer returns a running container summary on the given network
+func fakeContainer(id string, name string, networkName string, ip string) container.Summary {
+	c := container.Summary{
+		ID:     id,
+		Names:  []string{"/" + name},
+		Image:  "nginx",
+		State:  container.StateRunning,
+		Status: "Up",
+		Labels: map[string]string{},
+		Ports:  []container.Port{{PrivatePort: 80, Type: "tcp"}},
+	}
+	c.HostConfig.NetworkMode = networkName
+	c.NetworkSettings = &container.NetworkSettingsSummary{Networks: map[string]*network.EndpointSettings{
+		networkName: {NetworkID: networkName + "-id", IPAddress: ip},
+	}}
+	return c
+}
+
+// runsOn makes the fake report Newt's own container, found by the hostname, on
+// the given networks so targets can be validated against it
+func (f *fakeAPI) runsOn(t *testing.T, networkNames ...string) {
+	t.Helper()
+	hostname, err := os.Hostname()
+	if err != nil {
+		t.Skip(err)
+	}
+	networks := make(map[string]*network.EndpointSettings)
+	for i, name := range networkNames {
+		networks[name] = &network.EndpointSettings{NetworkID: name + "-id", IPAddress: fmt.Sprintf("172.18.%d.254", i)}
+	}
+	if f.inspects == nil {
+		f.inspects = make(map[string]container.InspectResponse)
+	}
+	f.inspects[hostname] = container.InspectResponse{
+		ContainerJSONBase: &container.ContainerJSONBase{ID: "c0ffee000000", Name: "/" + hostname, HostConfig: &container.HostConfig{NetworkMode: container.NetworkMode(networkNames[0])}},
+		Config:            &container.Config{Hostname: hostname},
+		NetworkSettings:   &container.NetworkSettings{Networks: networks},
+	}
+}
+
+// newFakeClient returns a client for the fake, with a socket path unique to the test so cached listings don't leak between tests
+func newFakeClient(t *testing.T, api *fakeAPI) *Client {
+	t.Helper()
+	socketPath := "fake://" + t.Name()
+	InvalidateCache(socketPath)
+	t.Cleanup(func() { InvalidateCache(socketPath) })
+	return NewClientFromAPI(socketPath, api)
+}
+
+func TestNewClientFromAPIListContainers(t *testing.T) {
+	longID := strings.Repeat("a", 64)
+
+	tests := []struct {
+		name     string
+		api      *fakeAPI
+		opts     ListOptions
+		wantIDs  []string
+		wantErr  error
+		wantFail bool
+	}{
+		{
+			name:    "full IDs are shortened",
+			api:     &fakeAPI{summaries: []container.Summary{fakeContainer(longID, "web", "shop", "172.20.0.5")}},
+			wantIDs: []string{longID[:12]},
+		},
+		{
+			name:    "short IDs are kept",
+			api:     &fakeAPI{summaries: []container.Summary{fakeContainer("abc", "web", "shop", "172.20.0.5")}},
+			wantIDs: []string{"abc"},
+		},
+		{
+			name: "excluded images are skipped",
+			api: &fakeAPI{summaries: []container.Summary{
+				fakeContainer("abc", "web", "shop", "172.20.0.5"),
+				func() container.Summary {
+					c := fakeContainer("def", "newt", "shop", "172.20.0.6")
+					c.Image = "fosrl/newt"
+					return c
+				}(),
+			}},
+			opts:    ListOptions{ExcludeImages: []string{"fosrl/newt"}},
+			wantIDs: []string{"abc"},
+		},
+		{
+			name: "inspect failures return partial results",
+			api: &fakeAPI{
+				summaries:  []container.Summary{fakeContainer("abc", "web", "shop", "172.20.0.5")},
+				inspectErr: map[string]error{"abc": cerrdefs.ErrInvalidArgument},
+			},
+			wantIDs: []string{"abc"},
+			wantErr: ErrPartialResults,
+		},
+		{
+			name:     "list failures fail the listing",
+			api:      &fakeAPI{listErr: cerrdefs.ErrInvalidArgument},
+			wantFail: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newFakeClient(t, tt.api)
+
+			containers, err := d.ListContainers(context.Background(), false, tt.opts)
+			if tt.wantFail {
+				if err == nil || containers != nil {
+					t.Fatalf("ListContainers = %v, %v, want no containers and an error", containers, err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ListContainers error = %v, want %v", err, tt.wantErr)
+			}
+
+			var ids []string
+			for _, c := range containers {
+				ids = append(ids, c.ID)
+				if c.SourceSocket != d.SocketPath() {
+					t.Errorf("container %s SourceSocket = %q, want %q", c.ID, c.SourceSocket, d.SocketPath())
+				}
+			}
+			if strings.Join(ids, ",") != strings.Join(tt.wantIDs, ",") {
+				t.Errorf("container IDs = %v, want %v", ids, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestNewClientFromAPIMatchContainer(t *testing.T) {
+	web := fakeContainer("a1b2c3d4e5f6", "web", "app", "172.18.0.2")
+	web.NetworkSettings.Networks["app"].Aliases = []string{"frontend"}
+
+	dns := fakeContainer("b1c2d3e4f5a6", "dns", "app", "172.18.0.3")
+	dns.Ports = []container.Port{{PrivatePort: 53, Type: "udp"}}
+
+	api1 := fakeContainer("c1d2e3f4a5b6", "api-1", "app", "172.18.0.4")
+	api1.NetworkSettings.Networks["app"].Aliases = []string{"api"}
+	api2 := fakeContainer("d1e2f3a4b5c6", "api-2", "app", "172.18.0.5")
+	api2.NetworkSettings.Networks["app"].Aliases = []string{"api"}
+
+	sick := fakeContainer("e1f2a3b4c5d6", "sick", "app", "172.18.0.6")
+
+	isolated := fakeContainer("f1a2b3c4d5e6", "isolated", "other", "172.19.0.2")
+
+	ranged := fakeContainer("a2b3c4d5e6f1", "ranged", "app", "172.18.0.7")
+	ranged.Ports = []container.Port{{PrivatePort: 9000, Type: "tcp"}, {PrivatePort: 9001, Type: "tcp"}, {PrivatePort: 9002, Type: "tcp"}}
+
+	published := fakeContainer("b2c3d4e5f6a1", "published", "app", "172.18.0.8")
+	published.Ports = []container.Port{
+		{PrivatePort: 443, PublicPort: 8443, Type: "tcp", IP: "0.0.0.0"},
+		{PrivatePort: 443, PublicPort: 9443, Type: "tcp", IP: "127.0.0.1"},
+	}
+
+	api := &fakeAPI{summaries: []container.Summary{web, dns, api1, api2, sick, isolated, ranged, published}}
+	api.runsOn(t, "app")
+	api.inspects[sick.ID] = container.InspectResponse{
+		ContainerJSONBase: &container.ContainerJSONBase{ID: sick.ID, Name: "/sick", State: &container.State{Status: container.StateRunning, Health: &container.Health{Status: container.Unhealthy}}},
+		Config:            &container.Config{Hostname: shortID(sick.ID), Labels: map[string]string{}},
+	}
+	d := newFakeClient(t, api)
+
+	tests := []struct {
+		name      string
+		target    string
+		startPort int
+		endPort   int
+		opts      ValidationOptions
+		want      string // name of the matched container, empty when the target is rejected
+		wantErr   error
+	}{
+		{name: "hostname", target: "web", startPort: 80, want: "web"},
+		{name: "ip address", target: "172.18.0.2", startPort: 80, want: "web"},
+		{name: "ip address without the port", target: "172.18.0.2", startPort: 81},
+		{name: "unknown hostname", target: "nginx", startPort: 80},
+		{name: "network alias", target: "frontend", startPort: 80, want: "web"},
+		{name: "ambiguous alias", target: "api", startPort: 80, wantErr: ErrAmbiguousMatch},
+		{name: "unambiguous ip of an aliased container", target: "172.18.0.5", startPort: 80, want: "api-2"},
+		{name: "protocol mismatch", target: "dns", startPort: 53, opts: ValidationOptions{Protocol: "tcp"}},
+		{name: "protocol match", target: "dns", startPort: 53, opts: ValidationOptions{Protocol: "udp"}, want: "dns"},
+		{name: "host gateway published port", target: "host.docker.internal", startPort: 8443, want: "published"},
+		{name: "host gateway unpublished port", target: "host.docker.internal", startPort: 8444},
+		{name: "host gateway loopback bind ip", target: "host.docker.internal", startPort: 9443, want: "published"},
+		{name: "host gateway unreachable bind ip", target: "host.docker.internal", startPort: 9443, opts: ValidationOptions{RequireReachableBindIP: true}},
+		{name: "unhealthy container", target: "sick", startPort: 80, want: "sick"},
+		{name: "unhealthy container rejected", target: "sick", startPort: 80, opts: ValidationOptions{RequireHealthy: true}},
+		{name: "no shared network", target: "isolated", startPort: 80, wantErr: ErrNoSharedNetwork},
+		{name: "port range", target: "ranged", startPort: 9000, endPort: 9002, want: "ranged"},
+		{name: "port range with a missing port", target: "ranged", startPort: 9000, endPort: 9003},
+		{name: "invalid port range", target: "ranged", startPort: 9002, endPort: 9000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			endPort := tt.endPort
+			if endPort == 0 {
+				endPort = tt.startPort
+			}
+			c, _, err := d.MatchContainerWithOptions(context.Background(), tt.target, tt.startPort, endPort, tt.opts)
+			if tt.want == "" {
+				if err == nil {
+					t.Fatalf("target %s was accepted by %s", tt.target, c.Name)
+				}
+				if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+					t.Errorf("error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.Name != tt.want {
+				t.Errorf("matched %s, want %s", c.Name, tt.want)
+			}
+
+			valid, err := d.IsWithinHostNetworkWithOptions(context.Background(), tt.target, tt.startPort, endPort, tt.opts)
+			if !valid || err != nil {
+				t.Errorf("IsWithinHostNetworkWithOptions = %t, %v, want valid", valid, err)
+			}
+		})
+	}
+}
+
+func TestShortID(t *testing.T) {
+	tests := []struct {
+		id   string
+		want string
+	}{
+		{"", ""},
+		{"abc", "abc"},
+		{"0123456789ab", "0123456789ab"},
+		{"0123456789abcdef", "0123456789ab"},
+	}
+	for _, tt := range tests {
+		if got := shortID(tt.id); got != tt.want {
+			t.Errorf("shortID(%q) = %q, want %q", tt.id, got, tt.want)
+		}
+	}
+}
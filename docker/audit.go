@@ -0,0 +1,53 @@
+package docker
+
+import "sync/atomic"
+
+// Reasons recorded for the address chosen for a target
+const (
+	TargetReasonLabel       = "label"        // TargetAddressLabel or TargetNameLabel set on the container
+	TargetReasonAddressMode = "address-mode" // configured AddressModeIP or AddressModeHostname
+	TargetReasonHeuristic   = "heuristic"    // bridge network heuristic of AddressModeAuto
+	TargetReasonPreference  = "preference"   // AddressResolver.Preference or DefaultAddressPreference
+)
+
+// TargetDecision records which address and port Newt chose for a container and why
+type TargetDecision struct {
+	ContainerID string `json:"containerId"`
+	Name        string `json:"name"`
+	Address     string `json:"address"`
+	Port        int    `json:"port"`   // port the accepted target points at
+	Reason      string `json:"reason"` // one of the TargetReason constants, preference reasons name the address kind
+}
+
+// auditHolder wraps the hook so atomic.Value always stores one concrete type
+type auditHolder struct {
+	hook func(TargetDecision)
+}
+
+var currentAuditHook atomic.Value
+
+// SetTargetAuditHook installs a hook that receives one decision for every target
+// accepted by validation, after probing, e.g. to keep an audit log. Rejected
+// targets are not reported. The hook is called synchronously and must be safe
+// for concurrent use. Passing nil removes it.
+func SetTargetAuditHook(hook func(TargetDecision)) {
+	currentAuditHook.Store(auditHolder{hook})
+}
+
+// auditTarget reports a decision to the installed hook
+func auditTarget(decision TargetDecision) {
+	if holder, ok := currentAuditHook.Load().(auditHolder); ok && holder.hook != nil {
+		holder.hook(decision)
+	}
+}
+
+// auditAcceptedTarget reports the container and port behind an accepted target.
+// The address is the one Newt advertises for the container, falling back to the
+// target itself when none resolves.
+func auditAcceptedTarget(c Container, targetAddress string, port int) {
+	address, reason, err := (AddressResolver{}).resolveTargetAddress(c)
+	if err != nil {
+		address, reason = targetAddress, ""
+	}
+	auditTarget(TargetDecision{ContainerID: c.ID, Name: c.Name, Address: address, Port: port, Reason: reason})
+}
@@ -0,0 +1,84 @@
+package docker
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// recordAudit installs an audit hook collecting the decisions until the test ends
+func recordAudit(t *testing.T) func() []TargetDecision {
+	var mu sync.Mutex
+	var decisions []TargetDecision
+	SetTargetAuditHook(func(decision TargetDecision) {
+		mu.Lock()
+		defer mu.Unlock()
+		decisions = append(decisions, decision)
+	})
+	t.Cleanup(func() { SetTargetAuditHook(nil) })
+	return func() []TargetDecision {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]TargetDecision(nil), decisions...)
+	}
+}
+
+func TestTargetAudit(t *testing.T) {
+	web := fakeContainer("a1b2c3d4e5f6", "web", "app", "172.18.0.2")
+	web.Ports = []container.Port{{PrivatePort: 80, Type: "tcp"}, {PrivatePort: 8080, Type: "tcp"}}
+	api := &fakeAPI{summaries: []container.Summary{web}}
+	api.runsOn(t, "app")
+	dockerClient := newFakeClient(t, api)
+	ctx := context.Background()
+
+	decisions := recordAudit(t)
+	if _, _, err := dockerClient.MatchContainerWithOptions(ctx, "web", 8080, 8080, ValidationOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	got := decisions()
+	if len(got) != 1 {
+		t.Fatalf("audited %d decisions, want 1: %+v", len(got), got)
+	}
+	if got[0].ContainerID != "a1b2c3d4e5f6" || got[0].Name != "web" || got[0].Port != 8080 {
+		t.Errorf("decision = %+v, want web on port 8080", got[0])
+	}
+
+	// Mapping a container to a target is not a validation decision
+	if _, err := ToTarget(Container{Name: "web", TargetAddress: "web", Ports: []Port{{PrivatePort: 80, Type: "tcp"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(decisions()); n != 1 {
+		t.Errorf("audited %d decisions after resolving addresses, want 1", n)
+	}
+}
+
+func TestTargetAuditRejected(t *testing.T) {
+	api := &fakeAPI{summaries: []container.Summary{fakeContainer("a1b2c3d4e5f6", "web.invalid", "app", "172.18.0.2")}}
+	api.runsOn(t, "app")
+	dockerClient := newFakeClient(t, api)
+	ctx := context.Background()
+
+	decisions := recordAudit(t)
+	if _, _, err := dockerClient.MatchContainerWithOptions(ctx, "web.invalid", 443, 443, ValidationOptions{}); err == nil {
+		t.Fatal("a port the container does not serve was accepted")
+	}
+	// The reserved .invalid domain never resolves, so the probe rejects the target
+	opts := ValidationOptions{Probe: true, ProbeTimeout: 100 * time.Millisecond}
+	if _, _, err := dockerClient.MatchContainerWithOptions(ctx, "web.invalid", 80, 80, opts); err == nil {
+		t.Fatal("a target failing the probe was accepted")
+	}
+	if got := decisions(); len(got) != 0 {
+		t.Errorf("rejected targets were audited: %+v", got)
+	}
+
+	// Without the probe the same target is accepted and audited
+	if _, _, err := dockerClient.MatchContainerWithOptions(ctx, "web.invalid", 80, 80, ValidationOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := decisions(); len(got) != 1 || got[0].Port != 80 {
+		t.Errorf("decisions = %+v, want one on port 80", got)
+	}
+}
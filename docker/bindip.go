@@ -0,0 +1,106 @@
+package docker
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/fosrl/newt/logger"
+)
+
+// UnreachablePort is a published port bound to an address Newt can't route to
+type UnreachablePort struct {
+	Container string `json:"container"` // container name
+	Port      Port   `json:"port"`
+}
+
+// UnreachablePublishedPorts returns the ports published on a specific host address
+// Newt can't reach. Without a container of its own, Newt can only reach addresses
+// of its local interfaces. Inside a container, host addresses are routed through
+// the gateway, but ports bound to the host's loopback remain unreachable.
+func UnreachablePublishedPorts(containers []Container) []UnreachablePort {
+	if selfContainerID() != "" {
+		return unreachablePorts(containers, nil, true)
+	}
+	if !publishesOnSpecificAddress(containers) {
+		// Nothing to compare, don't enumerate the interfaces
+		return nil
+	}
+
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		logger.Debug("Failed to list local interface addresses: %v", err)
+		return nil
+	}
+	var local []net.IP
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok {
+			local = append(local, ipNet.IP)
+		}
+	}
+	return unreachablePorts(containers, local, false)
+}
+
+// unreachablePorts implements UnreachablePublishedPorts. With inContainer set
+// only loopback binds are reported, otherwise binds to addresses missing from local.
+func unreachablePorts(containers []Container, local []net.IP, inContainer bool) []UnreachablePort {
+	var unreachable []UnreachablePort
+	for _, c := range containers {
+		for _, port := range c.Ports {
+			bindIP := net.ParseIP(port.IP)
+			if port.PublicPort == 0 || bindIP == nil || bindIP.IsUnspecified() {
+				continue
+			}
+			reachable := !bindIP.IsLoopback()
+			if !inContainer {
+				reachable = false
+				for _, ip := range local {
+					if ip.Equal(bindIP) {
+						reachable = true
+						break
+					}
+				}
+			}
+			if !reachable {
+				unreachable = append(unreachable, UnreachablePort{Container: c.Name, Port: port})
+			}
+		}
+	}
+	return unreachable
+}
+
+// publishesOnSpecificAddress reports whether a port is published on an address
+// other than the wildcard ones
+func publishesOnSpecificAddress(containers []Container) bool {
+	for _, c := range containers {
+		for _, port := range c.Ports {
+			if bindIP := net.ParseIP(port.IP); port.PublicPort != 0 && bindIP != nil && !bindIP.IsUnspecified() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// unreachablePortWarnings holds the socket, container and port of every
+// unreachable port already logged, so relisting does not repeat the warning
+var unreachablePortWarnings sync.Map
+
+// warnUnreachablePublishedPorts logs a warning once for every published port
+// Newt can't reach. Remote daemons are skipped as their host addresses can't be
+// compared with Newt's interfaces.
+func warnUnreachablePublishedPorts(socketPath string, host string, containers []Container) {
+	if !strings.HasPrefix(host, "unix://") && !strings.HasPrefix(host, "npipe://") {
+		return
+	}
+	for _, u := range UnreachablePublishedPorts(containers) {
+		key := fmt.Sprintf("%s/%s/%s:%d/%s", host, u.Container, u.Port.IP, u.Port.PublicPort, u.Port.Type)
+		if _, warned := unreachablePortWarnings.LoadOrStore(key, struct{}{}); warned {
+			continue
+		}
+		logger.WithFields(logger.Fields{"socketPath": socketPath, "container": u.Container}).Warn(
+			"Port %d/%s is only published on %s, which Newt can't reach, so targets on it will fail",
+			u.Port.PublicPort, u.Port.Type, u.Port.IP)
+	}
+}
@@ -0,0 +1,129 @@
+package docker
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// DefaultCacheTTL is how long a container listing is reused by default
+const DefaultCacheTTL = 10 * time.Second
+
+// cacheEntry holds a single container snapshot
+type cacheEntry struct {
+	containers []Container
+	expires    time.Time
+}
+
+// containerCache stores container snapshots per normalized Docker host, see
+// Client.host, so every spelling of a socket shares its entries. Each host keeps
+// one entry per distinct set of list options, as they yield different results.
+type containerCache struct {
+	mu      sync.RWMutex
+	entries map[string]map[listCacheKey]cacheEntry
+}
+
+var defaultCache = &containerCache{entries: make(map[string]map[listCacheKey]cacheEntry)}
+
+// cacheTTL returns the configured cache TTL or the default
+func (o ListOptions) cacheTTL() time.Duration {
+	if o.CacheTTL == 0 {
+		return DefaultCacheTTL
+	}
+	return o.CacheTTL
+}
+
+// listCacheKey identifies a listing in the cache. It is comparable, so it is
+// used as the map key directly.
+type listCacheKey struct {
+	enforceNetworkValidation bool
+	options                  string // the ListOptions that influence the result, see cacheKey
+}
+
+// cacheKey derives the cache key from every ListOptions field except those that
+// only control how the daemon is queried. New fields are part of the key without
+// further changes, so options yielding different results never share an entry.
+func cacheKey(enforceNetworkValidation bool, opts ListOptions) listCacheKey {
+	opts.Timeout = 0
+	opts.Concurrency = 0
+	opts.CacheTTL = 0
+	opts.ForceRefresh = false
+	opts.Retry = RetryPolicy{}
+
+	// JSON sorts map keys, including those of the filters, so equal options give equal keys
+	options, err := json.Marshal(opts)
+	if err != nil {
+		options = []byte(fmt.Sprintf("%#v", opts))
+	}
+	return listCacheKey{enforceNetworkValidation: enforceNetworkValidation, options: string(options)}
+}
+
+// get returns a deep copy of the cached snapshot if it has not expired
+func (c *containerCache) get(host string, key listCacheKey) ([]Container, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, ok := c.entries[host][key]
+	if !ok || clockNow().After(entry.expires) {
+		return nil, false
+	}
+
+	return cloneContainers(entry.containers), true
+}
+
+// set stores a snapshot for the host, a non-positive ttl disables caching
+func (c *containerCache) set(host string, key listCacheKey, containers []Container, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+
+	stored := cloneContainers(containers)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries[host] == nil {
+		c.entries[host] = make(map[listCacheKey]cacheEntry)
+	}
+	c.entries[host][key] = cacheEntry{containers: stored, expires: clockNow().Add(ttl)}
+}
+
+// cloneContainers deep-copies containers so callers can't modify a cached snapshot
+// while another goroutine reads it
+func cloneContainers(containers []Container) []Container {
+	cloned := make([]Container, len(containers))
+	for i, c := range containers {
+		c.Ports = slices.Clone(c.Ports)
+		c.Labels = maps.Clone(c.Labels)
+		c.Env = maps.Clone(c.Env)
+		c.Mounts = slices.Clone(c.Mounts)
+		c.Args = slices.Clone(c.Args)
+		if c.Networks != nil {
+			networks := make(map[string]Network, len(c.Networks))
+			for name, network := range c.Networks {
+				network.Aliases = slices.Clone(network.Aliases)
+				network.DNSNames = slices.Clone(network.DNSNames)
+				networks[name] = network
+			}
+			c.Networks = networks
+		}
+		cloned[i] = c
+	}
+	return cloned
+}
+
+// invalidate drops every snapshot for the normalized host
+func (c *containerCache) invalidate(host string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, host)
+}
+
+// InvalidateCache discards cached container listings for the given socket so the
+// next ListContainers call queries the Docker daemon again
+func InvalidateCache(socketPath string) {
+	defaultCache.invalidate(normalizeDockerHost(socketPath))
+}
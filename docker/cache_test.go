@@ -0,0 +1,78 @@
+package docker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/filters"
+)
+
+func TestCacheKey(t *testing.T) {
+	base := ListOptions{LabelSelectors: []string{"newt.enable"}, Filters: filters.NewArgs(filters.Arg("status", "running"), filters.Arg("name", "web"))}
+
+	same := base
+	same.Filters = filters.NewArgs(filters.Arg("name", "web"), filters.Arg("status", "running"))
+	same.Timeout = time.Minute
+	same.Concurrency = 3
+	same.CacheTTL = time.Hour
+	same.ForceRefresh = true
+	same.Retry = RetryPolicy{Attempts: 1}
+	if cacheKey(false, base) != cacheKey(false, same) {
+		t.Errorf("options differing only in how the daemon is queried have different keys")
+	}
+
+	changed := []ListOptions{
+		{LabelSelectors: []string{"newt.enable=true"}, Filters: base.Filters},
+		{LabelSelectors: base.LabelSelectors, Filters: filters.NewArgs(filters.Arg("status", "exited"))},
+		{LabelSelectors: base.LabelSelectors, Filters: base.Filters, ExcludePorts: []PortRange{{Start: 9000, End: 9100}}},
+		{LabelSelectors: base.LabelSelectors, Filters: base.Filters, LabelKeys: []string{"traefik.*"}},
+		{LabelSelectors: base.LabelSelectors, Filters: base.Filters, MinUptime: time.Minute},
+	}
+	for _, opts := range changed {
+		if cacheKey(false, base) == cacheKey(false, opts) {
+			t.Errorf("options %+v share the key of %+v", opts, base)
+		}
+	}
+	if cacheKey(false, base) == cacheKey(true, base) {
+		t.Errorf("network validation does not change the key")
+	}
+}
+
+func TestCacheExpiry(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	SetClock(func() time.Time { return now })
+	defer SetClock(nil)
+
+	cache := &containerCache{entries: make(map[string]map[listCacheKey]cacheEntry)}
+	key := cacheKey(false, ListOptions{})
+	cache.set("unix:///test.sock", key, []Container{{ID: "a"}}, 10*time.Second)
+
+	now = now.Add(10 * time.Second)
+	if containers, ok := cache.get("unix:///test.sock", key); !ok || len(containers) != 1 {
+		t.Errorf("entry expired at its TTL: %v, %t", containers, ok)
+	}
+
+	now = now.Add(time.Nanosecond)
+	if _, ok := cache.get("unix:///test.sock", key); ok {
+		t.Errorf("entry is still returned after its TTL")
+	}
+
+	cache.set("unix:///test.sock", key, []Container{{ID: "a"}}, 0)
+	if _, ok := cache.get("unix:///test.sock", key); ok {
+		t.Errorf("a zero TTL cached the listing")
+	}
+}
+
+func TestCacheReturnsCopies(t *testing.T) {
+	cache := &containerCache{entries: make(map[string]map[listCacheKey]cacheEntry)}
+	key := cacheKey(false, ListOptions{})
+	cache.set("unix:///test.sock", key, []Container{{ID: "a", Labels: map[string]string{"k": "v"}}}, time.Minute)
+
+	containers, _ := cache.get("unix:///test.sock", key)
+	containers[0].Labels["k"] = "changed"
+
+	containers, _ = cache.get("unix:///test.sock", key)
+	if containers[0].Labels["k"] != "v" {
+		t.Errorf("modifying a returned listing changed the cache")
+	}
+}
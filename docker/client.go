@@ -2,20 +2,32 @@ package docker
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"net"
+	"net/http"
 	"os"
+	"path"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	cerrdefs "github.com/containerd/errdefs"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/client"
 	"github.com/fosrl/newt/logger"
 )
 
-// Container represents a Docker container
+// Container represents a Docker container. It is sent to Pangolin as JSON, so json
+// tags are part of the wire format and must not be renamed. Fields tagged
+// omitempty are left out when they hold their zero value (0, false, "" or an
+// empty slice/map); consumers must treat a missing field as that zero value.
 type Container struct {
 	ID       string             `json:"id"`
 	Name     string             `json:"name"`
@@ -24,18 +36,143 @@ type Container struct {
 	Status   string             `json:"status"`
 	Ports    []Port             `json:"ports"`
 	Labels   map[string]string  `json:"labels"`
-	Created  int64              `json:"created"`
+	Created  int64              `json:"created"` // creation time in seconds since the Unix epoch, see CreatedAt
 	Networks map[string]Network `json:"networks"`
 	Hostname string             `json:"hostname"` // added to use hostname if available instead of network address
+	Health   string             `json:"health"`   // healthcheck status: healthy, unhealthy, starting or empty without a healthcheck
 
+	// TargetAddress is the address Newt selected for Pangolin targets: a container
+	// IP when IP addresses are used (see AddressMode), otherwise the hostname.
+	// A valid TargetAddressLabel overrides both.
+	TargetAddress string `json:"targetAddress,omitempty"`
+
+	// TargetReason tells why TargetAddress was chosen, one of the TargetReason constants
+	TargetReason string `json:"targetReason,omitempty"`
+
+	// TargetPort is the port set by TargetPortLabel, 0 when the label is unset
+	TargetPort int `json:"targetPort,omitempty"`
+
+	// TargetTTL is how long the container should be advertised after it started,
+	// set by TargetTTLLabel. 0 means no limit, see TargetExpired. Serialized in
+	// nanoseconds like any time.Duration.
+	TargetTTL time.Duration `json:"targetTtl,omitempty"`
+
+	RestartCount int  `json:"restartCount"`
+	OOMKilled    bool `json:"oomKilled"`
+
+	ComposeProject string `json:"composeProject,omitempty"`
+	ComposeService string `json:"composeService,omitempty"`
+
+	Mounts []Mount `json:"mounts,omitempty"`
+
+	// Command is the container entrypoint, or the full command line when the
+	// container could not be inspected. Args holds the arguments passed to it.
+	Command string   `json:"command,omitempty"`
+	Args    []string `json:"args,omitempty"`
+
+	// Resource limits from the host config, 0 when unlimited or unknown. CPUQuota
+	// is in microseconds per CPU period, MemoryLimit in bytes.
+	CPUQuota    int64 `json:"cpuQuota,omitempty"`
+	CPUShares   int64 `json:"cpuShares,omitempty"`
+	MemoryLimit int64 `json:"memoryLimit,omitempty"`
+
+	// StartedAt is when the container was last started, zero when unknown
+	StartedAt time.Time `json:"startedAt,omitzero"`
+
+	// IsHostNetwork is set for containers running in network mode 'host'. They
+	// share the host's network stack, so their exposed ports are listed as
+	// published on the host.
+	IsHostNetwork bool `json:"isHostNetwork,omitempty"`
+
+	// SourceSocket is the socket path or Docker host URI the container was discovered on
+	SourceSocket string `json:"sourceSocket,omitempty"`
+
+	// Env holds the environment variables whose name starts with ListOptions.EnvPrefix.
+	// Nothing is exposed unless a prefix is configured, as environment variables
+	// often contain secrets.
+	Env map[string]string `json:"env,omitempty"`
+
+	// IPConflict is set when another listed container has the same IP address on
+	// one of the networks, see DuplicateIPAddresses
+	IPConflict bool `json:"ipConflict,omitempty"`
+
+	// AddressError explains why TargetAddress is the hostname although IP
+	// addresses are used, e.g. an ErrNoAddressOfFamily error for an IPv6 only
+	// AddressFamily on an IPv4 network. Empty when the address is as requested.
+	AddressError string `json:"addressError,omitempty"`
+
+	// fullID is the complete container ID, ID holds the short form. Validation
+	// matches targets against it, it is not sent to Pangolin.
+	fullID string
+}
+
+// IsUnhealthy reports whether the container's healthcheck is currently failing.
+// Containers without a healthcheck are never considered unhealthy.
+func (c Container) IsUnhealthy() bool {
+	return c.Health == container.Unhealthy
+}
+
+// CreatedAt returns the container creation time
+func (c Container) CreatedAt() time.Time {
+	return time.Unix(c.Created, 0)
+}
+
+// TargetExpired reports whether the container outlived its TargetTTL at now, so
+// the caller should stop advertising it. Containers without a TTL never expire.
+// The TTL counts from StartedAt, or from creation when the start time is unknown.
+func (c Container) TargetExpired(now time.Time) bool {
+	expiresAt, ok := c.targetExpiresAt()
+	return ok && !now.Before(expiresAt)
+}
+
+// targetExpiresAt returns when the container outlives its TargetTTL, false without a TTL
+func (c Container) targetExpiresAt() (time.Time, bool) {
+	if c.TargetTTL <= 0 {
+		return time.Time{}, false
+	}
+	started := c.StartedAt
+	if started.IsZero() {
+		started = c.CreatedAt()
+	}
+	return started.Add(c.TargetTTL), true
+}
+
+// IsRoutable reports whether the container is attached to at least one network
+// Newt could reach it on. Containers without networks or running with network
+// mode none cannot be targets.
+func IsRoutable(c Container) bool {
+	for networkName := range c.Networks {
+		if routableNetwork(networkName) {
+			return true
+		}
+	}
+	return false
+}
+
+// routableNetwork reports whether a network can carry traffic to the container
+func routableNetwork(networkName string) bool {
+	return networkName != "none"
 }
 
-// Port represents a port mapping for a Docker container
+// Port represents a port mapping for a Docker container. publicPort is omitted
+// from the JSON when the port is only exposed and not published on the host, so
+// a missing publicPort means "not published" rather than port 0.
 type Port struct {
 	PrivatePort int    `json:"privatePort"`
 	PublicPort  int    `json:"publicPort,omitempty"`
 	Type        string `json:"type"`
 	IP          string `json:"ip,omitempty"`
+	DualStack   bool   `json:"dualStack,omitempty"` // published on both 0.0.0.0 and ::, see ListOptions.MergeDualStackPorts
+	Exposed     bool   `json:"exposed,omitempty"`   // declared with EXPOSE but not published, only reachable via the container IP
+}
+
+// Mount represents a volume or bind mount of a Docker container
+type Mount struct {
+	Type        string `json:"type"`
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	Mode        string `json:"mode,omitempty"`
+	RW          bool   `json:"rw"`
 }
 
 // Network represents network information for a Docker container
@@ -51,11 +188,244 @@ type Network struct {
 	MacAddress          string   `json:"macAddress,omitempty"`
 	Aliases             []string `json:"aliases,omitempty"`
 	DNSNames            []string `json:"dnsNames,omitempty"`
+	Subnet              string   `json:"subnet,omitempty"`   // subnet of the Docker network in CIDR notation, e.g. 172.18.0.0/16
+	Internal            bool     `json:"internal,omitempty"` // network created with --internal, unknown with SkipInspect
+}
+
+// DefaultTimeout is the default upper bound for a single Docker API call
+const DefaultTimeout = 5 * time.Second
+
+// DefaultConcurrency is the default number of containers inspected in parallel
+const DefaultConcurrency = 8
+
+// ListOptions controls how ListContainers talks to the Docker daemon
+type ListOptions struct {
+	// Timeout bounds each individual Docker API call (the container list and
+	// every per-container inspect) rather than the whole enumeration, so one
+	// slow container does not fail the entire list. Defaults to DefaultTimeout.
+	Timeout time.Duration
+
+	// Concurrency limits how many ContainerInspect calls run in parallel.
+	// Defaults to DefaultConcurrency.
+	Concurrency int
+
+	// LabelSelectors restricts the listed containers to those matching every
+	// selector. Selectors are either a label key ("newt.enable") or a key=value
+	// pair ("newt.enable=true"). No filtering is applied when empty.
+	LabelSelectors []string
+
+	// ExcludeLabelSelectors drops containers matching any of these selectors
+	// (same syntax as LabelSelectors), e.g. DisableLabelSelector. They are
+	// checked after every other filter, so an opt-out always wins, even on a
+	// container that LabelSelectors would select.
+	ExcludeLabelSelectors []string
+
+	// IncludeStopped also returns created, exited and paused containers. By default
+	// only running containers are listed as only they can serve as targets.
+	IncludeStopped bool
+
+	// CacheTTL controls how long a listing is reused for identical requests
+	// against the same socket. Defaults to DefaultCacheTTL, negative disables caching.
+	CacheTTL time.Duration
+
+	// ForceRefresh bypasses the cache and stores the fresh result
+	ForceRefresh bool
+
+	// Retry controls retries of transient Docker API failures
+	Retry RetryPolicy
+
+	// ExcludeImages drops containers whose image starts with any of these
+	// prefixes (e.g. "fosrl/newt"), so Newt never targets itself or a sibling
+	// Newt instance even when the host container can't be found by hostname.
+	// Registry prefixes like docker.io/ are ignored when matching.
+	ExcludeImages []string
+
+	// AddressMode overrides the bridge network heuristic that decides whether
+	// container IP addresses or hostnames are sent to Pangolin. Defaults to AddressModeAuto.
+	AddressMode AddressMode
+
+	// AddressFamily selects IPv4, IPv6 or dual stack addresses when container IP
+	// addresses are sent to Pangolin. Defaults to AddressFamilyIPv4.
+	AddressFamily AddressFamily
+
+	// Offset skips this many containers before listing, after the host container
+	// and excluded images are removed. Only containers on the page are inspected,
+	// unless MinUptime is set.
+	Offset int
+
+	// Limit caps the number of returned containers, 0 means no limit
+	Limit int
+
+	// SkipInspect skips the per-container and per-network inspects so discovery
+	// costs a single ContainerList call (plus the host container lookup). Hostname,
+	// Health, RestartCount, OOMKilled, Mounts, Args and network subnets are then
+	// left empty, and targets use container names or IPs.
+	SkipInspect bool
+
+	// PortLabels are label keys whose value is the port a container serves on,
+	// e.g. "traefik.http.services.*.loadbalancer.server.port". Keys may contain
+	// * wildcards. Matching ports are added to Ports when the container does not
+	// already list them, which covers containers that neither expose nor publish
+	// the port.
+	PortLabels []string
+
+	// RequirePublishedPorts drops containers that publish no port on the host.
+	// Off by default as targets on the bridge network use private ports directly.
+	RequirePublishedPorts bool
+
+	// Filters are passed through to ContainerList (e.g. status, name or ancestor)
+	// and merged with the label selectors. When network validation is enforced,
+	// network filters are replaced by the host container's networks.
+	Filters filters.Args
+
+	// MinUptime drops running containers that started less than this long ago,
+	// giving slow starting apps time to bind their ports. Containers whose start
+	// time is unknown (see SkipInspect) are kept. Offset and Limit are applied
+	// after this filter, so every candidate is inspected to learn its start time.
+	MinUptime time.Duration
+
+	// Ancestors restricts discovery to containers created from one of these images
+	// or their descendants, given as image[:tag], image ID or image@digest
+	// (Docker's "ancestor" filter).
+	Ancestors []string
+
+	// Networks restricts discovery to containers attached to one of these network
+	// names. With network validation enforced, only the allowed networks Newt is
+	// attached to are used. All networks are used when empty.
+	Networks []string
+
+	// SortBy orders the containers before Offset and Limit are applied, so
+	// pages and diffs are stable. Defaults to the daemon's order.
+	SortBy SortKey
+
+	// MergeDualStackPorts collapses a port published on both 0.0.0.0 and :: into
+	// a single Port bound to 0.0.0.0 with DualStack set. By default both raw
+	// entries are returned.
+	MergeDualStackPorts bool
+
+	// RoutableOnly drops containers that are not attached to any usable
+	// network (see IsRoutable)
+	RoutableOnly bool
+
+	// PreferUserNetworks takes the IP address of multi-homed containers from a
+	// user defined network (not bridge, host or none, and not internal) before
+	// any other network. Ties are broken by network name, as the Docker API does
+	// not report the order networks were attached in.
+	PreferUserNetworks bool
+
+	// ExcludePorts drops these private or published ports from Ports, so admin
+	// interfaces are never advertised or validated as targets. Containers can
+	// exclude further ports with ExcludePortsLabel.
+	ExcludePorts []PortRange
+
+	// NewtNetwork scopes discovery to containers on this network, e.g. a dedicated
+	// "newt" network Newt joins. Unlike Networks and network validation, Newt's
+	// other networks are ignored: only containers on this network are listed,
+	// whether validation is enforced or not, their IP addresses are taken from it,
+	// and nothing is listed when Newt's container is found but not attached to it.
+	NewtNetwork string
+
+	// Names restricts discovery to containers whose name matches one of these
+	// path.Match patterns, e.g. "web-*" for numbered replicas (see
+	// ValidationOptions.GlobNames for the syntax). All names are used when empty.
+	Names []string
+
+	// EnvPrefix exposes the container environment variables starting with this
+	// prefix (e.g. "NEWT_") as Container.Env. Empty, the default, exposes none,
+	// as environment variables often contain secrets. Requires inspecting, see SkipInspect.
+	EnvPrefix string
+
+	// LabelKeys restricts Container.Labels to the label keys matching one of these
+	// path.Match patterns, e.g. "traefik.*" for a prefix. All labels are kept when
+	// empty. Newt's own labels are still evaluated before they are dropped.
+	LabelKeys []string
+}
+
+// timeout returns the configured per-call timeout or the default
+func (o ListOptions) timeout() time.Duration {
+	if o.Timeout <= 0 {
+		return DefaultTimeout
+	}
+	return o.Timeout
+}
+
+// concurrency returns the configured inspect concurrency or the default
+func (o ListOptions) concurrency() int {
+	if o.Concurrency <= 0 {
+		return DefaultConcurrency
+	}
+	return o.Concurrency
+}
+
+var (
+	// ErrSocketNotFound is returned when the Docker socket path does not exist
+	ErrSocketNotFound = errors.New("docker socket does not exist")
+	// ErrSocketPermissionDenied is returned when the Docker socket exists but can't be opened
+	ErrSocketPermissionDenied = errors.New("permission denied accessing docker socket")
+	// ErrHostContainerNotFound is returned when Newt's own container can't be found, which
+	// is expected when Newt is not running in a container or runs in network mode 'host'
+	ErrHostContainerNotFound = errors.New("host container not found")
+	// ErrInspectForbidden is returned when the Docker API allows listing but denies
+	// inspecting containers, e.g. behind a restrictive socket proxy
+	ErrInspectForbidden = errors.New("container inspect forbidden by the docker API")
+	// ErrPartialResults is joined with the per-container errors when some containers
+	// could not be inspected. The containers are still returned, with list-only data
+	// for the failed ones, so callers can decide whether that is acceptable.
+	ErrPartialResults = errors.New("some containers could not be inspected")
+	// ErrNoSharedNetwork is returned by validation when the target container exists
+	// but is not attached to any network Newt is attached to
+	ErrNoSharedNetwork = errors.New("target container shares no network with newt")
+	// ErrAPIVersionTooOld is returned when the negotiated Docker API version is
+	// below ClientOptions.MinAPIVersion
+	ErrAPIVersionTooOld = errors.New("docker API version too old")
+	// ErrNoAddressOfFamily is recorded in Container.AddressError when IP addresses
+	// are used but the container has none of the requested AddressFamily
+	ErrNoAddressOfFamily = errors.New("no address of the requested family")
+)
+
+// defaultBridgeGateway is the gateway of Docker's default bridge network (docker0)
+const defaultBridgeGateway = "172.17.0.1"
+
+// hostGatewayNames are hostnames that resolve to the Docker host from inside containers
+var hostGatewayNames = []string{"host.docker.internal", "host-gateway", "gateway.docker.internal", "host.containers.internal"}
+
+// DefaultSocketPath is used when no Docker socket path is configured
+const DefaultSocketPath = "unix:///var/run/docker.sock"
+
+// inspectForbiddenWarning makes sure the list-only fallback warning is only logged once per process
+var inspectForbiddenWarning sync.Once
+
+// warnInspectForbidden logs once that discovery falls back to the container list
+func warnInspectForbidden() {
+	inspectForbiddenWarning.Do(func() {
+		logger.Warn("The Docker API denies container inspect (e.g. a socket proxy without CONTAINERS access to inspect), " +
+			"falling back to list-only data: hostnames, health, mounts and host network ports are unavailable")
+	})
+}
+
+// validationDisabledWarning makes sure the unvalidated discovery warning is only logged once per process
+var validationDisabledWarning sync.Once
+
+// warnValidationDisabled logs once that discovery is not restricted to Newt's networks.
+// It is called where the user's setting is applied, see NewClientsFromConfig, as
+// validation itself lists containers without network validation.
+func warnValidationDisabled() {
+	validationDisabledWarning.Do(func() {
+		logger.Warn("Docker network validation is disabled: every container is reported regardless of whether Newt shares a network with it, " +
+			"so targets may point at arbitrary addresses. Enable it with --docker-enforce-network-validation")
+	})
+}
+
+// TLSConfig holds the client certificates used to reach a TLS protected Docker daemon
+type TLSConfig struct {
+	CAFile   string
+	CertFile string
+	KeyFile  string
 }
 
 // Strcuture parts of docker api endpoint
 type dockerHost struct {
-	protocol string // e.g. unix, http, tcp, ssh
+	protocol string // e.g. unix, npipe, tcp, ssh
 	address  string // e.g. "/var/run/docker.sock" or "host:port"
 }
 
@@ -64,6 +434,8 @@ func parseDockerHost(raw string) (dockerHost, error) {
 	switch {
 	case strings.HasPrefix(raw, "unix://"):
 		return dockerHost{"unix", strings.TrimPrefix(raw, "unix://")}, nil
+	case strings.HasPrefix(raw, "npipe://"):
+		return dockerHost{"npipe", strings.TrimPrefix(raw, "npipe://")}, nil
 	case strings.HasPrefix(raw, "ssh://"):
 		// SSH is treated as TCP-like transport by the docker client
 		return dockerHost{"ssh", strings.TrimPrefix(raw, "ssh://")}, nil
@@ -72,6 +444,10 @@ func parseDockerHost(raw string) (dockerHost, error) {
 		s = strings.TrimPrefix(s, "tcp://")
 		s = strings.TrimPrefix(s, "http://")
 		s = strings.TrimPrefix(s, "https://")
+		// Drop any base path, only host:port is dialable
+		if i := strings.Index(s, "/"); i >= 0 {
+			s = s[:i]
+		}
 		return dockerHost{"tcp", s}, nil
 	case strings.HasPrefix(raw, "/"):
 		// Absolute path without scheme - treat as unix socket
@@ -82,242 +458,1702 @@ func parseDockerHost(raw string) (dockerHost, error) {
 	}
 }
 
-// CheckSocket checks if Docker socket is available
-func CheckSocket(socketPath string) bool {
-	// Use the provided socket path or default to standard location
+// normalizeDockerHost turns a socket path or host URI into a URI the Docker client accepts
+func normalizeDockerHost(socketPath string) string {
+	// Like the docker CLI, honor DOCKER_HOST and DOCKER_CONTEXT when no socket is provided
 	if socketPath == "" {
-		socketPath = "unix:///var/run/docker.sock"
+		if host := hostFromEnvironment(); host != "" {
+			return normalizeDockerHost(host)
+		}
+	}
+
+	// Detect the socket when none or "auto" is provided
+	if socketPath == "" || socketPath == AutoSocketPath {
+		return detectSocket()
+	}
+
+	// The Docker client only understands tcp:// for remote daemons, TLS is negotiated separately
+	if strings.HasPrefix(socketPath, "http://") {
+		return "tcp://" + strings.TrimPrefix(socketPath, "http://")
+	}
+	if strings.HasPrefix(socketPath, "https://") {
+		return "tcp://" + strings.TrimPrefix(socketPath, "https://")
 	}
 
-	// Ensure the socket path is properly formatted
+	// If no scheme provided, assume unix socket
 	if !strings.Contains(socketPath, "://") {
-		// If no scheme provided, assume unix socket
-		socketPath = "unix://" + socketPath
+		return "unix://" + socketPath
 	}
 
-	host, err := parseDockerHost(socketPath)
-	if err != nil {
-		logger.Debug("Invalid Docker socket path '%s': %v", socketPath, err)
-		return false
+	return socketPath
+}
+
+// newDockerClient creates a Docker client for the host, as returned by
+// normalizeDockerHost. Explicit TLS files take precedence, otherwise
+// DOCKER_CERT_PATH and DOCKER_TLS_VERIFY are honored.
+func newDockerClient(host string, clientOptions ClientOptions) (*client.Client, error) {
+	var opts []client.Opt
+
+	// TLS options must come first as the env variant replaces the HTTP client
+	tlsConfig := clientOptions.TLS
+	if tlsConfig != nil && (tlsConfig.CAFile != "" || tlsConfig.CertFile != "" || tlsConfig.KeyFile != "") {
+		opts = append(opts, client.WithTLSClientConfig(tlsConfig.CAFile, tlsConfig.CertFile, tlsConfig.KeyFile))
+	} else {
+		opts = append(opts, client.WithTLSClientConfigFromEnv())
 	}
-	protocol := host.protocol
-	addr := host.address
 
-	// ssh might need different verification, but tcp works for basic reachability
-	conn, err := net.DialTimeout(protocol, addr, 2*time.Second)
+	if strings.HasPrefix(host, "ssh://") {
+		// Like the Docker CLI, tunnel the API through "docker system dial-stdio" on the remote host
+		dialer, err := sshDialer(host)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, client.WithHost(sshHost), client.WithDialContext(dialer))
+	} else {
+		opts = append(opts, client.WithHost(host))
+	}
+	opts = append(opts, client.WithAPIVersionNegotiation())
+
+	cli, err := client.NewClientWithOpts(opts...)
 	if err != nil {
-		logger.Debug("Docker not reachable via %s at %s: %v", protocol, addr, err)
-		return false
+		return nil, fmt.Errorf("failed to create Docker client: %v", err)
 	}
-	defer conn.Close()
 
-	logger.Debug("Docker reachable via %s at %s", protocol, addr)
-	return true
+	// HTTPClient returns a copy of the client that shares its transport
+	if transport, ok := cli.HTTPClient().Transport.(*http.Transport); ok {
+		transport.MaxIdleConns = clientOptions.maxIdleConns()
+		// Every request goes to the same daemon, so the per host limit (2 by default) is the effective one
+		transport.MaxIdleConnsPerHost = clientOptions.maxIdleConns()
+		transport.IdleConnTimeout = clientOptions.idleConnTimeout()
+	}
+	return cli, nil
 }
 
-// IsWithinHostNetwork checks if a provided target is within the host container network
-func IsWithinHostNetwork(socketPath string, targetAddress string, targetPort int) (bool, error) {
-	// Always enforce network validation
-	containers, err := ListContainers(socketPath, true)
+// Connection pool defaults, matching the Docker client's own defaults
+const (
+	DefaultMaxIdleConns    = 6
+	DefaultIdleConnTimeout = 30 * time.Second
+)
+
+// ClientOptions configures a Client
+type ClientOptions struct {
+	// TLS configures client certificates for a remote tcp:// daemon. When nil,
+	// DOCKER_CERT_PATH and DOCKER_TLS_VERIFY are used if set.
+	TLS *TLSConfig
+
+	// MaxIdleConns caps the idle keep-alive connections to the daemon. Raise it
+	// when inspect concurrency is high, so connections are reused instead of
+	// re-established. Defaults to DefaultMaxIdleConns.
+	MaxIdleConns int
+
+	// IdleConnTimeout closes keep-alive connections that were idle this long.
+	// Defaults to DefaultIdleConnTimeout.
+	IdleConnTimeout time.Duration
+
+	// MinAPIVersion rejects daemons whose negotiated API version is older, e.g.
+	// "1.44" for the DNSNames of network endpoints, so missing data from an
+	// outdated daemon surfaces as ErrAPIVersionTooOld. No minimum when empty.
+	MinAPIVersion string
+}
+
+// maxIdleConns returns the configured idle connection cap or the default
+func (o ClientOptions) maxIdleConns() int {
+	if o.MaxIdleConns <= 0 {
+		return DefaultMaxIdleConns
+	}
+	return o.MaxIdleConns
+}
+
+// idleConnTimeout returns the configured idle connection timeout or the default
+func (o ClientOptions) idleConnTimeout() time.Duration {
+	if o.IdleConnTimeout <= 0 {
+		return DefaultIdleConnTimeout
+	}
+	return o.IdleConnTimeout
+}
+
+// Client is a reusable connection to a Docker daemon. Creating it once avoids the
+// per-call client setup and API version negotiation done by the package level
+// functions, which remain as thin wrappers around a short lived Client. A Client
+// is safe for concurrent use.
+type Client struct {
+	socketPath    string
+	host          string // socketPath normalized once, as that may stat sockets and read Docker contexts
+	cli           API
+	versionLogged sync.Once
+
+	minAPIVersion string
+	apiCheckMu    sync.Mutex
+	apiChecked    bool  // set once the negotiated version was compared with minAPIVersion
+	apiErr        error // result of that comparison
+}
+
+// NewClient creates a Client for the given socket path or Docker host URI
+func NewClient(socketPath string, tlsConfig *TLSConfig) (*Client, error) {
+	return NewClientWithOptions(socketPath, ClientOptions{TLS: tlsConfig})
+}
+
+// NewClientWithOptions creates a Client with explicit TLS and connection pool settings
+func NewClientWithOptions(socketPath string, opts ClientOptions) (*Client, error) {
+	if opts.MinAPIVersion != "" && !apiVersionPattern.MatchString(opts.MinAPIVersion) {
+		return nil, fmt.Errorf("invalid minimum Docker API version %q: expected a version such as 1.44", opts.MinAPIVersion)
+	}
+	// Resolve DOCKER_HOST and the docker context once, the client keeps using this daemon
+	host := normalizeDockerHost(socketPath)
+	cli, err := newDockerClient(host, opts)
 	if err != nil {
+		return nil, err
+	}
+	return &Client{socketPath: socketPath, host: host, cli: cli, minAPIVersion: opts.MinAPIVersion}, nil
+}
+
+// SocketPath returns the socket path or Docker host URI the client was created with
+func (d *Client) SocketPath() string {
+	return d.socketPath
+}
+
+// Close releases the connection to the Docker daemon
+func (d *Client) Close() error {
+	return d.cli.Close()
+}
+
+// CheckSocket checks if the client's Docker socket is available
+func (d *Client) CheckSocket(ctx context.Context) bool {
+	return CheckSocket(ctx, d.host)
+}
+
+// CheckSocketWithError checks if the client's Docker socket is available and
+// returns the reason when it is not
+func (d *Client) CheckSocketWithError(ctx context.Context) (bool, error) {
+	available, err := CheckSocketWithError(ctx, d.host)
+	if !available {
+		return false, err
+	}
+	if err := d.checkAPIVersion(ctx); err != nil {
 		return false, err
 	}
+	return true, nil
+}
 
-	// Determine if given an IP address
-	var parsedTargetAddressIp = net.ParseIP(targetAddress)
+// CheckSocket checks if Docker socket is available. socketPath may be a bare
+// socket path or a unix://, tcp://, npipe:// or ssh:// Docker host URI.
+func CheckSocket(ctx context.Context, socketPath string) bool {
+	available, _ := CheckSocketWithError(ctx, socketPath)
+	return available
+}
 
-	// If we can find the passed hostname/IP address in the networks or as the container name, it is valid and can add it
-	for _, c := range containers {
-		for _, network := range c.Networks {
-			// If the target address is not an IP address, use the container name
-			if parsedTargetAddressIp == nil {
-				if c.Name == targetAddress {
-					for _, port := range c.Ports {
-						if port.PublicPort == targetPort || port.PrivatePort == targetPort {
-							return true, nil
-						}
-					}
-				}
-			} else {
-				//If the IP address matches, check the ports being mapped too
-				if network.IPAddress == targetAddress {
-					for _, port := range c.Ports {
-						if port.PublicPort == targetPort || port.PrivatePort == targetPort {
-							return true, nil
-						}
-					}
-				}
-			}
+// CheckSocketWithError checks if Docker socket is available and returns the reason
+// when it is not. The error wraps ErrSocketNotFound or ErrSocketPermissionDenied
+// when the socket is missing or not accessible by the current user.
+func CheckSocketWithError(ctx context.Context, socketPath string) (bool, error) {
+	socketPath = normalizeDockerHost(socketPath)
+
+	host, err := parseDockerHost(socketPath)
+	if err != nil {
+		logger.WithFields(logger.Fields{"socketPath": socketPath}).Debug("Invalid Docker socket path: %v", err)
+		return false, fmt.Errorf("invalid Docker socket path '%s': %w", socketPath, err)
+	}
+	protocol := host.protocol
+	addr := host.address
+	log := logger.WithFields(logger.Fields{"socketPath": socketPath, "protocol": protocol})
+
+	// Named pipes and SSH can't be dialed with net.Dial, ask the daemon directly instead
+	if protocol == "npipe" || protocol == "ssh" {
+		cli, err := newDockerClient(socketPath, ClientOptions{})
+		if err != nil {
+			log.Debug("Docker not reachable: %v", err)
+			return false, err
 		}
+		defer cli.Close()
+
+		// SSH needs time for the handshake and starting the remote docker CLI
+		timeout := 2 * time.Second
+		if protocol == "ssh" {
+			timeout = 15 * time.Second
+		}
+		pingCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		if _, err := cli.Ping(pingCtx); err != nil {
+			log.Debug("Docker not reachable: %v", err)
+			return false, classifySocketError(protocol, addr, err)
+		}
+
+		log.Debug("Docker reachable")
+		return true, nil
 	}
 
-	combinedTargetAddress := targetAddress + ":" + strconv.Itoa(targetPort)
-	return false, fmt.Errorf("target address not within host container network: %s", combinedTargetAddress)
+	dialer := net.Dialer{Timeout: 2 * time.Second}
+	conn, err := dialer.DialContext(ctx, protocol, addr)
+	if err != nil {
+		log.Debug("Docker not reachable: %v", err)
+		return false, classifySocketError(protocol, addr, err)
+	}
+	defer conn.Close()
+
+	log.Debug("Docker reachable")
+	return true, nil
 }
 
-// ListContainers lists all Docker containers with their network information
-func ListContainers(socketPath string, enforceNetworkValidation bool) ([]Container, error) {
-	// Use the provided socket path or default to standard location
-	if socketPath == "" {
-		socketPath = "unix:///var/run/docker.sock"
+// classifySocketError wraps a dial error with a sentinel describing the likely fix
+func classifySocketError(protocol, addr string, err error) error {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return fmt.Errorf("%w: %s: %v", ErrSocketNotFound, addr, err)
+	case errors.Is(err, fs.ErrPermission):
+		return fmt.Errorf("%w: %s (is the user in the docker group?): %v", ErrSocketPermissionDenied, addr, err)
+	default:
+		return fmt.Errorf("docker not reachable via %s at %s: %w", protocol, addr, err)
 	}
+}
 
-	// Ensure the socket path is properly formatted for the Docker client
-	if !strings.Contains(socketPath, "://") {
-		// If no scheme provided, assume unix socket
-		socketPath = "unix://" + socketPath
-	}
+// IsWithinHostNetwork checks if a provided target is within the host container network
+func IsWithinHostNetwork(ctx context.Context, socketPath string, targetAddress string, targetPort int) (bool, error) {
+	return IsWithinHostNetworkRange(ctx, socketPath, targetAddress, targetPort, targetPort)
+}
 
-	// Used to filter down containers returned to Pangolin
-	containerFilters := filters.NewArgs()
+// IsWithinHostNetwork checks if a provided target is within the host container network
+func (d *Client) IsWithinHostNetwork(ctx context.Context, targetAddress string, targetPort int) (bool, error) {
+	return d.IsWithinHostNetworkRange(ctx, targetAddress, targetPort, targetPort)
+}
 
-	// Used to determine if we will send IP addresses or hostnames to Pangolin
-	useContainerIpAddresses := true
-	hostContainerId := ""
-
-	// Create a new Docker client
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	// Create client with custom socket path
-	cli, err := client.NewClientWithOpts(
-		client.WithHost(socketPath),
-		client.WithAPIVersionNegotiation(),
-	)
+// IsWithinHostNetworkRange checks if every port from startPort to endPort (inclusive)
+// is mapped by a single container within the host container network that matches
+// targetAddress. The error lists the missing ports of the closest matching container.
+func IsWithinHostNetworkRange(ctx context.Context, socketPath string, targetAddress string, startPort int, endPort int) (bool, error) {
+	return IsWithinHostNetworkWithOptions(ctx, socketPath, ClientOptions{}, targetAddress, startPort, endPort, ValidationOptions{})
+}
+
+// IsWithinHostNetworkRange checks if every port from startPort to endPort (inclusive)
+// is mapped by a single container within the host container network
+func (d *Client) IsWithinHostNetworkRange(ctx context.Context, targetAddress string, startPort int, endPort int) (bool, error) {
+	return d.IsWithinHostNetworkWithOptions(ctx, targetAddress, startPort, endPort, ValidationOptions{})
+}
+
+// ValidationOptions controls how targets are matched against containers.
+// The zero value preserves the lenient default behavior.
+type ValidationOptions struct {
+	// RequireReachableBindIP only accepts a published port when it is bound to
+	// a wildcard address (0.0.0.0 or ::) or to the target address itself, so
+	// ports published on e.g. 127.0.0.1 are not treated as reachable.
+	RequireReachableBindIP bool
+
+	// Protocol restricts matching to ports of the given type ("tcp" or "udp"),
+	// so a UDP-only service is not accepted as a TCP target. Empty matches any.
+	Protocol string
+
+	// LenientNameMatching trims whitespace around the target and compares
+	// container names and aliases case-insensitively, so " MyApp " matches a
+	// container named "myapp". Matching is exact by default.
+	LenientNameMatching bool
+
+	// GlobNames treats hostname targets as path.Match patterns, so "web-*"
+	// matches every container or alias starting with "web-". * matches any run
+	// of characters, ? a single character and [a-z] a character class. The
+	// first matching container with all ports is used. Names match literally
+	// by default.
+	GlobNames bool
+
+	// RequireHealthy rejects targets whose container healthcheck reports
+	// unhealthy, even when the port is mapped. Containers without a healthcheck
+	// are accepted.
+	RequireHealthy bool
+
+	// Networks restricts validation to containers on these networks, see ListOptions.Networks
+	Networks []string
+
+	// ExcludePorts are never accepted as target ports, see ListOptions.ExcludePorts
+	ExcludePorts []PortRange
+
+	// Probe dials every TCP port of a target that passed validation, so a port
+	// that is published but has no process listening is rejected. Each dial is
+	// bounded by ProbeTimeout. UDP targets are not probed.
+	Probe bool
+
+	// ProbeTimeout bounds each probe dial. Defaults to DefaultProbeTimeout.
+	ProbeTimeout time.Duration
+}
+
+// DefaultProbeTimeout is the default upper bound for a single probe dial
+const DefaultProbeTimeout = 2 * time.Second
+
+// probeTimeout returns the configured probe timeout or the default
+func (o ValidationOptions) probeTimeout() time.Duration {
+	if o.ProbeTimeout <= 0 {
+		return DefaultProbeTimeout
+	}
+	return o.ProbeTimeout
+}
+
+// IsWithinHostNetworkWithOptions is IsWithinHostNetworkRange with explicit client
+// and validation options, e.g. the TLS configuration of a remote daemon
+func IsWithinHostNetworkWithOptions(ctx context.Context, socketPath string, clientOpts ClientOptions, targetAddress string, startPort int, endPort int, opts ValidationOptions) (bool, error) {
+	dockerClient, err := NewClientWithOptions(socketPath, clientOpts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create Docker client: %v", err)
+		return false, err
 	}
+	defer dockerClient.Close()
+
+	return dockerClient.IsWithinHostNetworkWithOptions(ctx, targetAddress, startPort, endPort, opts)
+}
 
-	defer cli.Close()
+// IsWithinHostNetworkWithOptions is IsWithinHostNetworkRange with explicit validation options
+func (d *Client) IsWithinHostNetworkWithOptions(ctx context.Context, targetAddress string, startPort int, endPort int, opts ValidationOptions) (bool, error) {
+	_, _, err := d.MatchContainerWithOptions(ctx, targetAddress, startPort, endPort, opts)
+	return err == nil, err
+}
 
-	hostContainer, err := getHostContainer(ctx, cli)
-	if enforceNetworkValidation && err != nil {
-		return nil, fmt.Errorf("network validation enforced, cannot validate due to: %w", err)
+// MatchContainer returns the container and port that make the target valid, see
+// IsWithinHostNetwork. Callers that need the container's address can build the
+// target from the result instead of searching again.
+func MatchContainer(ctx context.Context, socketPath string, clientOpts ClientOptions, targetAddress string, targetPort int) (*Container, *Port, error) {
+	dockerClient, err := NewClientWithOptions(socketPath, clientOpts)
+	if err != nil {
+		return nil, nil, err
 	}
+	defer dockerClient.Close()
 
-	// We may not be able to get back host container in scenarios like running the container in network mode 'host'
-	if hostContainer != nil {
-		// We can use the host container to filter out the list of returned containers
-		hostContainerId = hostContainer.ID
+	return dockerClient.MatchContainer(ctx, targetAddress, targetPort)
+}
 
-		for hostContainerNetworkName := range hostContainer.NetworkSettings.Networks {
-			// If we're enforcing network validation, we'll filter on the host containers networks
-			if enforceNetworkValidation {
-				containerFilters.Add("network", hostContainerNetworkName)
-			}
+// MatchContainer returns the container and port that make the target valid, see IsWithinHostNetwork
+func (d *Client) MatchContainer(ctx context.Context, targetAddress string, targetPort int) (*Container, *Port, error) {
+	return d.MatchContainerWithOptions(ctx, targetAddress, targetPort, targetPort, ValidationOptions{})
+}
 
-			// If the container is on the docker bridge network, we will use IP addresses over hostnames
-			if useContainerIpAddresses && hostContainerNetworkName != "bridge" {
-				useContainerIpAddresses = false
+// MatchContainerWithOptions returns the container and the Port entry of startPort
+// that make the target valid under the validation options. For host gateway
+// targets this is the container publishing startPort on the host. An error is
+// returned whenever the target is not valid.
+func (d *Client) MatchContainerWithOptions(ctx context.Context, targetAddress string, startPort int, endPort int, opts ValidationOptions) (*Container, *Port, error) {
+	return d.matchWithSnapshot(ctx, d.newValidationSnapshot(opts), targetAddress, startPort, endPort, opts)
+}
+
+// matchWithSnapshot implements MatchContainerWithOptions against the listings of snap
+func (d *Client) matchWithSnapshot(ctx context.Context, snap *validationSnapshot, targetAddress string, startPort int, endPort int, opts ValidationOptions) (*Container, *Port, error) {
+	c, port, err := d.matchContainer(ctx, snap, targetAddress, startPort, endPort, opts)
+	if err == nil && opts.Probe && opts.Protocol != "udp" {
+		probeAddress := targetAddress
+		if opts.GlobNames && c != nil {
+			// A pattern can't be dialed, probe the matched container instead
+			if resolved, _, resolveErr := (AddressResolver{}).resolveTargetAddress(*c); resolveErr == nil {
+				probeAddress = resolved
 			}
 		}
+		err = probeTarget(ctx, probeAddress, startPort, endPort, opts.probeTimeout())
 	}
-
-	// List containers
-	containers, err := cli.ContainerList(ctx, container.ListOptions{All: true, Filters: containerFilters})
+	metrics().ValidationCompleted(d.socketPath, err == nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list containers: %v", err)
+		return nil, nil, err
 	}
+	auditAcceptedTarget(*c, targetAddress, startPort)
+	return c, port, nil
+}
 
-	var dockerContainers []Container
-	for _, c := range containers {
-		// Short ID like docker ps
-		shortId := c.ID[:12]
+// matchContainer implements MatchContainerWithOptions
+func (d *Client) matchContainer(ctx context.Context, snap *validationSnapshot, targetAddress string, startPort int, endPort int, opts ValidationOptions) (*Container, *Port, error) {
+	if startPort < 1 || endPort > 65535 || startPort > endPort {
+		return nil, nil, fmt.Errorf("invalid port range: %d-%d", startPort, endPort)
+	}
+	if opts.Protocol != "" && opts.Protocol != "tcp" && opts.Protocol != "udp" {
+		return nil, nil, fmt.Errorf("invalid protocol: %q (expected tcp or udp)", opts.Protocol)
+	}
 
-		// Inspect container to get hostname
-		hostname := ""
-		containerInfo, err := cli.ContainerInspect(ctx, c.ID)
-		if err == nil && containerInfo.Config != nil {
-			hostname = containerInfo.Config.Hostname
-		}
+	if opts.LenientNameMatching {
+		targetAddress = strings.TrimSpace(targetAddress)
+	}
 
-		// Skip host container if set
-		if hostContainerId != "" && c.ID == hostContainerId {
+	// Determine if given an IP address
+	var parsedTargetAddressIp = net.ParseIP(targetAddress)
+
+	// Targets on the host itself are reached through ports published by any container
+	isGateway, err := isHostGateway(ctx, snap, targetAddress, parsedTargetAddressIp)
+	if err != nil {
+		return nil, nil, err
+	}
+	if isGateway {
+		return validateHostGatewayTarget(ctx, snap, targetAddress, parsedTargetAddressIp, startPort, endPort, opts)
+	}
+
+	// Always enforce network validation
+	containers, err := snap.networkContainers(ctx)
+	if fatalListError(err) {
+		return nil, nil, err
+	}
+
+	// If we can find the passed hostname/IP address in the networks or as the container name, it is valid and can add it
+	var closestMissing []int
+	var unhealthy []string
+	var candidates []Container
+	for _, c := range containers {
+		if !containerMatchesAddress(c, targetAddress, parsedTargetAddressIp, opts) {
 			continue
 		}
-
-		// Get container name (remove leading slash)
-		name := ""
-		if len(c.Names) > 0 {
-			name = strings.TrimPrefix(c.Names[0], "/")
+		if opts.RequireHealthy && c.IsUnhealthy() {
+			unhealthy = append(unhealthy, c.Name)
+			continue
 		}
 
-		// Convert ports
-		var ports []Port
-		for _, port := range c.Ports {
-			dockerPort := Port{
-				PrivatePort: int(port.PrivatePort),
-				Type:        port.Type,
-			}
-			if port.PublicPort != 0 {
-				dockerPort.PublicPort = int(port.PublicPort)
-			}
-			if port.IP != "" {
-				dockerPort.IP = port.IP
+		// Check the ports being mapped too
+		var missing []int
+		for port := startPort; port <= endPort; port++ {
+			if !containerHasPort(c, port, parsedTargetAddressIp, opts) {
+				missing = append(missing, port)
 			}
-			ports = append(ports, dockerPort)
-		}
-
-		// Get network information by inspecting the container
-		networks := make(map[string]Network)
-
-		// Extract network information from inspection
-		if c.NetworkSettings != nil && c.NetworkSettings.Networks != nil {
-			for networkName, endpoint := range c.NetworkSettings.Networks {
-				dockerNetwork := Network{
-					NetworkID:           endpoint.NetworkID,
-					EndpointID:          endpoint.EndpointID,
-					Gateway:             endpoint.Gateway,
-					IPPrefixLen:         endpoint.IPPrefixLen,
-					IPv6Gateway:         endpoint.IPv6Gateway,
-					GlobalIPv6Address:   endpoint.GlobalIPv6Address,
-					GlobalIPv6PrefixLen: endpoint.GlobalIPv6PrefixLen,
-					MacAddress:          endpoint.MacAddress,
-					Aliases:             endpoint.Aliases,
-					DNSNames:            endpoint.DNSNames,
-				}
-
-				// Use IPs over hostnames/containers as we're on the bridge network
-				if useContainerIpAddresses {
-					dockerNetwork.IPAddress = endpoint.IPAddress
-				}
+		}
+		if len(missing) == 0 {
+			candidates = append(candidates, c)
+			continue
+		}
+		if closestMissing == nil || len(missing) < len(closestMissing) {
+			closestMissing = missing
+		}
+	}
 
-				networks[networkName] = dockerNetwork
+	if len(candidates) > 0 {
+		// Glob patterns select several containers on purpose, names must not
+		if parsedTargetAddressIp == nil && !opts.GlobNames {
+			if err := ambiguousMatch(targetAddress, candidates); err != nil {
+				return nil, nil, err
 			}
 		}
+		c := candidates[0]
+		return &c, matchingPort(c, startPort, parsedTargetAddressIp, opts), nil
+	}
 
-		dockerContainer := Container{
-			ID:       shortId,
-			Name:     name,
-			Image:    c.Image,
-			State:    c.State,
-			Status:   c.Status,
-			Ports:    ports,
-			Labels:   c.Labels,
-			Created:  c.Created,
-			Networks: networks,
-			Hostname: hostname, // added
+	combinedTargetAddress := net.JoinHostPort(targetAddress, strconv.Itoa(startPort))
+	if endPort != startPort {
+		combinedTargetAddress += "-" + strconv.Itoa(endPort)
+	}
+	if closestMissing != nil && endPort != startPort {
+		return nil, nil, fmt.Errorf("target address not within host container network: %s (missing ports: %s)", combinedTargetAddress, formatPorts(closestMissing))
+	}
+	if closestMissing == nil && len(unhealthy) > 0 {
+		return nil, nil, fmt.Errorf("target %s refers to unhealthy container(s): %s", combinedTargetAddress, strings.Join(unhealthy, ", "))
+	}
+	if closestMissing == nil {
+		if err := noSharedNetworkError(ctx, snap, targetAddress, parsedTargetAddressIp, opts); err != nil {
+			return nil, nil, err
 		}
-
-		dockerContainers = append(dockerContainers, dockerContainer)
 	}
-
-	return dockerContainers, nil
+	return nil, nil, fmt.Errorf("target address not within host container network: %s", combinedTargetAddress)
 }
 
-// getHostContainer gets the current container for the current host if possible
-func getHostContainer(dockerContext context.Context, dockerClient *client.Client) (*container.InspectResponse, error) {
-	// Get hostname from the os
-	hostContainerName, err := os.Hostname()
+// noSharedNetworkError returns an ErrNoSharedNetwork error naming the networks of
+// the container matching the target and Newt's networks, or nil when no container
+// matches or Newt's own container is unknown
+func noSharedNetworkError(ctx context.Context, snap *validationSnapshot, targetAddress string, targetIp net.IP, opts ValidationOptions) error {
+	hostContainer, err := snap.hostContainer(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to find hostname for container")
+		return nil
 	}
+	hostNetworks := inspectNetworks(hostContainer)
 
-	// Get host container from the docker socket
-	hostContainer, err := dockerClient.ContainerInspect(dockerContext, hostContainerName)
-	if err != nil {
-		return nil, fmt.Errorf("failed to find host container")
+	containers, err := snap.allContainers(ctx)
+	if fatalListError(err) {
+		return nil
+	}
+	for _, c := range containers {
+		if !containerMatchesAddress(c, targetAddress, targetIp, opts) || len(SharedNetworks(hostNetworks, c.Networks)) > 0 {
+			continue
+		}
+		return fmt.Errorf("%w: container %s is on networks [%s], newt is on networks [%s]",
+			ErrNoSharedNetwork, c.Name, strings.Join(sortedNetworkNames(c), ", "), strings.Join(sortedNetworkNames(Container{Networks: hostNetworks}), ", "))
+	}
+	return nil
+}
+
+// probeTarget dials every port in the range and returns an error naming the ports
+// that do not accept TCP connections
+func probeTarget(ctx context.Context, targetAddress string, startPort int, endPort int, timeout time.Duration) error {
+	dialer := net.Dialer{Timeout: timeout}
+	targetAddress = strings.TrimSpace(targetAddress)
+
+	var closed []int
+	for port := startPort; port <= endPort; port++ {
+		conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(targetAddress, strconv.Itoa(port)))
+		if err != nil {
+			logger.Debug("Probe of %s port %d failed: %v", targetAddress, port, err)
+			closed = append(closed, port)
+			continue
+		}
+		conn.Close()
+	}
+	if len(closed) > 0 {
+		return fmt.Errorf("target %s is not accepting connections on port(s) %s", targetAddress, formatPorts(closed))
+	}
+	return nil
+}
+
+// isHostGateway reports whether the target refers to the Docker host rather than a
+// container, either through a well known host alias, a network gateway address or
+// a loopback address when Newt runs outside of a container
+func isHostGateway(ctx context.Context, snap *validationSnapshot, targetAddress string, targetIp net.IP) (bool, error) {
+	if targetIp == nil {
+		for _, name := range hostGatewayNames {
+			if strings.EqualFold(targetAddress, name) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	if targetIp.Equal(net.ParseIP(defaultBridgeGateway)) {
+		return true, nil
+	}
+
+	// Loopback only reaches the host when Newt itself shares the host network stack,
+	// i.e. it is not running in its own container
+	if targetIp.IsLoopback() {
+		_, err := snap.hostContainer(ctx)
+		if errors.Is(err, ErrHostContainerNotFound) {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		return false, nil
+	}
+
+	// Gateways of user defined networks are only known from the containers attached to them
+	containers, err := snap.allContainers(ctx)
+	if fatalListError(err) {
+		return false, err
+	}
+	for _, c := range containers {
+		for _, network := range c.Networks {
+			for _, gateway := range []string{network.Gateway, network.IPv6Gateway} {
+				if gateway != "" && targetIp.Equal(net.ParseIP(gateway)) {
+					return true, nil
+				}
+			}
+		}
+	}
+	return false, nil
+}
+
+// validateHostGatewayTarget checks that every port in the range is published on the
+// host by some container. This works without knowing the host container, so it also
+// covers Newt running in network mode 'host'.
+func validateHostGatewayTarget(ctx context.Context, snap *validationSnapshot, targetAddress string, targetIp net.IP, startPort int, endPort int, opts ValidationOptions) (*Container, *Port, error) {
+	containers, err := snap.allContainers(ctx)
+	if fatalListError(err) {
+		return nil, nil, err
+	}
+
+	var missing []int
+	var first *Container
+	for port := startPort; port <= endPort; port++ {
+		published := false
+		for _, c := range containers {
+			if opts.RequireHealthy && c.IsUnhealthy() {
+				continue
+			}
+			if containerPublishesReachablePort(c, port, targetIp, opts) {
+				published = true
+				if first == nil {
+					first = &c
+				}
+				break
+			}
+		}
+		if !published {
+			missing = append(missing, port)
+		}
+	}
+	if len(missing) == 0 {
+		return first, publishedPort(*first, startPort, targetIp, opts), nil
+	}
+
+	return nil, nil, fmt.Errorf("no container publishes port(s) %s on host gateway %s", formatPorts(missing), targetAddress)
+}
+
+// containerMatchesAddress reports whether the target address refers to the container
+// on any of its networks. Hostnames match the container name or a network alias,
+// IP addresses match the IPv4 or IPv6 address of an endpoint, and the address
+// returned by ResolveTargetAddress always matches.
+func containerMatchesAddress(c Container, targetAddress string, targetIp net.IP, opts ValidationOptions) bool {
+	// The address Newt would advertise for the container always matches
+	if resolved, _, err := (AddressResolver{}).resolveTargetAddress(c); err == nil && len(c.Networks) > 0 && namesEqual(resolved, targetAddress, opts) {
+		return true
+	}
+
+	// The container ID picks one of several containers sharing a name
+	if targetIp == nil && len(c.Networks) > 0 && containerIDMatches(c, targetAddress) {
+		return true
+	}
+
+	for _, network := range c.Networks {
+		// If the target address is not an IP address, use the container name or its network aliases
+		if targetIp == nil {
+			if namesEqual(c.Name, targetAddress, opts) || networkHasName(network, targetAddress, opts) {
+				return true
+			}
+		} else if networkHasIP(network, targetIp) {
+			return true
+		}
+	}
+	return false
+}
+
+// ContainerPublishesPort reports whether the container publishes port on the host
+// for the protocol ("tcp" or "udp"). An empty protocol matches any. A port
+// published for both protocols has one Port entry per protocol.
+func ContainerPublishesPort(c Container, port int, proto string) bool {
+	for _, p := range c.Ports {
+		if p.PublicPort == port && protocolMatches(p, proto) {
+			return true
+		}
+	}
+	return false
+}
+
+// protocolMatches reports whether the port uses the protocol, an empty protocol matches any
+func protocolMatches(p Port, proto string) bool {
+	return proto == "" || strings.EqualFold(p.Type, proto)
+}
+
+// containerHasPort reports whether the container maps the port publicly or privately
+func containerHasPort(c Container, targetPort int, targetIp net.IP, opts ValidationOptions) bool {
+	for _, port := range c.Ports {
+		if protocolMatches(port, opts.Protocol) && port.PrivatePort == targetPort {
+			return true
+		}
+	}
+	return containerPublishesReachablePort(c, targetPort, targetIp, opts)
+}
+
+// matchingPort returns the port entry that made containerHasPort succeed, preferring
+// a private port match over a published one
+func matchingPort(c Container, targetPort int, targetIp net.IP, opts ValidationOptions) *Port {
+	for _, port := range c.Ports {
+		if protocolMatches(port, opts.Protocol) && port.PrivatePort == targetPort {
+			return &port
+		}
+	}
+	return publishedPort(c, targetPort, targetIp, opts)
+}
+
+// publishedPort returns the port entry that made containerPublishesReachablePort succeed
+func publishedPort(c Container, targetPort int, targetIp net.IP, opts ValidationOptions) *Port {
+	for _, port := range c.Ports {
+		if port.PublicPort == targetPort && protocolMatches(port, opts.Protocol) && (!opts.RequireReachableBindIP || bindIPReachable(port.IP, targetIp)) {
+			return &port
+		}
+	}
+	return nil
+}
+
+// containerPublishesReachablePort is ContainerPublishesPort honoring RequireReachableBindIP
+func containerPublishesReachablePort(c Container, targetPort int, targetIp net.IP, opts ValidationOptions) bool {
+	if !opts.RequireReachableBindIP {
+		return ContainerPublishesPort(c, targetPort, opts.Protocol)
+	}
+	for _, port := range c.Ports {
+		if port.PublicPort == targetPort && protocolMatches(port, opts.Protocol) && bindIPReachable(port.IP, targetIp) {
+			return true
+		}
+	}
+	return false
+}
+
+// bindIPReachable reports whether a port published on bindIP can be reached via targetIp
+func bindIPReachable(bindIP string, targetIp net.IP) bool {
+	parsedBindIp := net.ParseIP(bindIP)
+	if bindIP == "" || parsedBindIp.IsUnspecified() {
+		return true
+	}
+	return targetIp != nil && targetIp.Equal(parsedBindIp)
+}
+
+// formatPorts renders ports as a comma separated list
+func formatPorts(ports []int) string {
+	parts := make([]string, len(ports))
+	for i, port := range ports {
+		parts[i] = strconv.Itoa(port)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// networkHasName reports whether the name is one of the endpoint's aliases or DNS names,
+// e.g. the compose service name
+func networkHasName(network Network, name string, opts ValidationOptions) bool {
+	for _, alias := range network.Aliases {
+		if namesEqual(alias, name, opts) {
+			return true
+		}
+	}
+	for _, dnsName := range network.DNSNames {
+		if namesEqual(dnsName, name, opts) {
+			return true
+		}
+	}
+	return false
+}
+
+// namesEqual compares a container name or alias with a target. Lenient
+// comparison ignores surrounding whitespace and case, glob matching treats the
+// target as a path.Match pattern.
+func namesEqual(name string, target string, opts ValidationOptions) bool {
+	if opts.LenientNameMatching {
+		name, target = strings.ToLower(strings.TrimSpace(name)), strings.ToLower(strings.TrimSpace(target))
+	}
+	if opts.GlobNames {
+		// Malformed patterns fall back to a literal comparison
+		if matched, err := path.Match(target, name); err == nil {
+			return matched
+		}
+	}
+	return name == target
+}
+
+// nameMatchesPatterns reports whether the name matches one of the path.Match patterns
+func nameMatchesPatterns(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matched, err := path.Match(strings.TrimSpace(pattern), name); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// networkHasIP reports whether the network endpoint owns the given IPv4 or IPv6 address.
+// Addresses are compared as net.IP so compressed and expanded IPv6 forms match.
+func networkHasIP(network Network, ip net.IP) bool {
+	for _, address := range []string{network.IPAddress, network.GlobalIPv6Address} {
+		if address != "" && ip.Equal(net.ParseIP(address)) {
+			return true
+		}
+	}
+	return false
+}
+
+// ListContainers lists running Docker containers with their network information.
+// Each Docker API call is bounded by DefaultTimeout (5s) on top of any deadline
+// set on ctx, and results are reused for DefaultCacheTTL (10s), see InvalidateCache.
+func ListContainers(ctx context.Context, socketPath string, enforceNetworkValidation bool) ([]Container, error) {
+	return ListContainersWithOptions(ctx, socketPath, ClientOptions{}, enforceNetworkValidation, ListOptions{})
+}
+
+// ListContainersWithOptions lists Docker containers with their network information
+// using the provided options. Zero options behave like ListContainers.
+func ListContainersWithOptions(ctx context.Context, socketPath string, clientOpts ClientOptions, enforceNetworkValidation bool, opts ListOptions) ([]Container, error) {
+	dockerClient, err := NewClientWithOptions(socketPath, clientOpts)
+	if err != nil {
+		return nil, err
+	}
+	defer dockerClient.Close()
+
+	return dockerClient.ListContainers(ctx, enforceNetworkValidation, opts)
+}
+
+// ListContainers lists Docker containers with their network information using the
+// provided options. When some inspects fail, the containers are returned along with an error wrapping
+// ErrPartialResults; such partial listings are not cached.
+func (d *Client) ListContainers(ctx context.Context, enforceNetworkValidation bool, opts ListOptions) ([]Container, error) {
+	key := cacheKey(enforceNetworkValidation, opts)
+	if !opts.ForceRefresh {
+		if containers, ok := defaultCache.get(d.host, key); ok {
+			metrics().CacheHit(d.socketPath)
+			return containers, nil
+		}
+	}
+	metrics().CacheMiss(d.socketPath)
+
+	if err := d.checkAPIVersion(ctx); err != nil {
+		return nil, err
+	}
+
+	start := clockNow()
+	containers, err := d.listContainers(ctx, enforceNetworkValidation, opts)
+	metrics().ListCompleted(d.socketPath, clockSince(start), len(containers), err)
+	warnDuplicateMACAddresses(d.socketPath, containers)
+	warnUnreachablePublishedPorts(d.socketPath, d.host, containers)
+	markIPConflicts(d.socketPath, containers)
+	if err != nil {
+		if errors.Is(err, ErrPartialResults) {
+			return containers, err
+		}
+		return nil, err
+	}
+
+	defaultCache.set(d.host, key, containers, opts.cacheTTL())
+	return containers, nil
+}
+
+// listState carries the per-listing decisions shared by every container. It is
+// created fresh for every ListContainers, ForEachContainer or FindContainer call,
+// so memoized network inspects never outlive a single call. buildContainer runs
+// sequentially, so the networks map needs no locking.
+type listState struct {
+	hostContainerId         string
+	useContainerIpAddresses bool
+	networks                map[string]networkDetails // network ID to inspected details, memoized for the listing
+	addressReason           string                    // TargetReason for addresses chosen by useContainerIpAddresses
+}
+
+// listContainers queries the Docker daemon, bypassing the cache
+func (d *Client) listContainers(ctx context.Context, enforceNetworkValidation bool, opts ListOptions) ([]Container, error) {
+	containers, state, err := d.listSummaries(ctx, enforceNetworkValidation, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Inspect containers in parallel, results are indexed to preserve list order
+	inspects, inspectErr := d.inspectContainers(ctx, containers, state.hostContainerId, opts)
+
+	var dockerContainers []Container
+	for i, c := range containers {
+		dockerContainer := d.buildContainer(ctx, c, inspects[i], state, opts)
+		if startingUp(dockerContainer, opts.MinUptime) {
+			continue
+		}
+		dockerContainers = append(dockerContainers, dockerContainer)
+	}
+	if opts.MinUptime > 0 {
+		dockerContainers = paginate(dockerContainers, opts.Offset, opts.Limit)
+	}
+
+	return dockerContainers, inspectErr
+}
+
+// fatalListError reports whether a listing error left no usable containers
+func fatalListError(err error) bool {
+	return err != nil && !errors.Is(err, ErrPartialResults)
+}
+
+// ForEachContainer calls fn for every container matching the options, inspecting
+// them in batches of opts.Concurrency instead of building the whole list first.
+// This keeps memory bounded on hosts with thousands of containers. Iteration
+// stops at the first error returned by fn, which is then returned. Inspect
+// failures don't stop the iteration and are returned joined with
+// ErrPartialResults at the end. The cache is neither read nor updated.
+func (d *Client) ForEachContainer(ctx context.Context, enforceNetworkValidation bool, opts ListOptions, fn func(Container) error) error {
+	if err := d.checkAPIVersion(ctx); err != nil {
+		return err
+	}
+
+	containers, state, err := d.listSummaries(ctx, enforceNetworkValidation, opts)
+	if err != nil {
+		return err
+	}
+
+	// With MinUptime, listSummaries leaves paging to us as start times need inspects
+	skip, remaining := 0, -1
+	if opts.MinUptime > 0 {
+		skip = opts.Offset
+		if opts.Limit > 0 {
+			remaining = opts.Limit
+		}
+	}
+
+	var inspectErrs []error
+	batchSize := opts.concurrency()
+	for start := 0; start < len(containers); start += batchSize {
+		end := min(start+batchSize, len(containers))
+		batch := containers[start:end]
+
+		inspects, inspectErr := d.inspectContainers(ctx, batch, state.hostContainerId, opts)
+		if inspectErr != nil {
+			inspectErrs = append(inspectErrs, inspectErr)
+		}
+		for i, c := range batch {
+			dockerContainer := d.buildContainer(ctx, c, inspects[i], state, opts)
+			if startingUp(dockerContainer, opts.MinUptime) {
+				continue
+			}
+			if skip > 0 {
+				skip--
+				continue
+			}
+			if remaining == 0 {
+				return errors.Join(inspectErrs...)
+			}
+			if err := fn(dockerContainer); err != nil {
+				return err
+			}
+			if remaining > 0 {
+				remaining--
+			}
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+
+	return errors.Join(inspectErrs...)
+}
+
+// ForEachContainer calls fn for every container on the given socket, see Client.ForEachContainer
+func ForEachContainer(ctx context.Context, socketPath string, clientOpts ClientOptions, enforceNetworkValidation bool, opts ListOptions, fn func(Container) error) error {
+	dockerClient, err := NewClientWithOptions(socketPath, clientOpts)
+	if err != nil {
+		return err
+	}
+	defer dockerClient.Close()
+
+	return dockerClient.ForEachContainer(ctx, enforceNetworkValidation, opts, fn)
+}
+
+// listSummaries lists the containers to report, without the host container and
+// excluded images, applying opts.Offset and opts.Limit unless opts.MinUptime is set
+func (d *Client) listSummaries(ctx context.Context, enforceNetworkValidation bool, opts ListOptions) ([]container.Summary, listState, error) {
+	// Used to filter down containers returned to Pangolin, starting from the caller's filters
+	containerFilters := opts.Filters.Clone()
+
+	// Only include containers matching the requested labels
+	for _, selector := range opts.LabelSelectors {
+		selector = strings.TrimSpace(selector)
+		if selector != "" {
+			containerFilters.Add("label", selector)
+		}
+	}
+
+	// Ancestor filters are OR'ed, matching any of the images
+	for _, ancestor := range trimmedValues(opts.Ancestors) {
+		containerFilters.Add("ancestor", ancestor)
+	}
+
+	// Used to determine if we will send IP addresses or hostnames to Pangolin
+	state := listState{useContainerIpAddresses: true, networks: make(map[string]networkDetails)}
+
+	cli := d.cli
+
+	hostContainer, err := withRetry(ctx, opts, "host container inspect", func(ctx context.Context) (*container.InspectResponse, error) {
+		return getHostContainer(ctx, cli)
+	})
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrHostContainerNotFound), errors.Is(err, ErrInspectForbidden):
+		if errors.Is(err, ErrInspectForbidden) {
+			warnInspectForbidden()
+		}
+		// Not running in a container is fine unless we have to validate against its networks
+		if enforceNetworkValidation {
+			return nil, state, fmt.Errorf("network validation enforced, cannot validate due to: %w", err)
+		}
+	default:
+		// Anything else (permissions, daemon unreachable) would fail the listing as well
+		return nil, state, err
+	}
+
+	// We may not be able to get back host container in scenarios like running the container in network mode 'host'
+	allowedNetworks := trimmedValues(opts.Networks)
+
+	// A dedicated Newt network replaces the allowed networks and, for the filter and
+	// the address heuristic, every other network Newt is attached to
+	newtNetwork := strings.TrimSpace(opts.NewtNetwork)
+	if newtNetwork != "" {
+		allowedNetworks = []string{newtNetwork}
+		if hostContainer != nil && hostContainer.NetworkSettings != nil {
+			if _, ok := hostContainer.NetworkSettings.Networks[newtNetwork]; !ok {
+				logger.Warn("Newt is not attached to its network %s, no containers can be discovered", newtNetwork)
+				return nil, state, nil
+			}
+		}
+	}
+
+	// Network filters are OR'ed, so a caller's network filter would widen the enforced or allowed set
+	if enforceNetworkValidation || len(allowedNetworks) > 0 {
+		for _, network := range containerFilters.Get("network") {
+			containerFilters.Del("network", network)
+		}
+	}
+
+	enforcedNetworks := 0
+	if hostContainer != nil {
+		// We can use the host container to filter out the list of returned containers
+		state.hostContainerId = hostContainer.ID
+
+		for _, hostContainerNetworkName := range sortedNetworkNames(Container{Networks: inspectNetworks(hostContainer)}) {
+			if newtNetwork != "" && hostContainerNetworkName != newtNetwork {
+				continue
+			}
+
+			// If we're enforcing network validation, we'll filter on the allowed host containers networks
+			if enforceNetworkValidation && (len(allowedNetworks) == 0 || slices.Contains(allowedNetworks, hostContainerNetworkName)) {
+				containerFilters.Add("network", hostContainerNetworkName)
+				enforcedNetworks++
+			}
+
+			// If the container is on the docker bridge network, we will use IP addresses over hostnames
+			if state.useContainerIpAddresses && hostContainerNetworkName != "bridge" {
+				state.useContainerIpAddresses = false
+			}
+		}
+	}
+
+	if enforceNetworkValidation && len(allowedNetworks) > 0 && enforcedNetworks == 0 {
+		// An empty network filter would match every container
+		logger.Warn("Newt is not attached to any of the allowed networks %s, no containers can be validated", strings.Join(allowedNetworks, ", "))
+		return nil, state, nil
+	}
+	if !enforceNetworkValidation {
+		for _, network := range allowedNetworks {
+			containerFilters.Add("network", network)
+		}
+	}
+
+	// Let the configured mode override the heuristic
+	state.useContainerIpAddresses = opts.AddressMode.useIPAddresses(state.useContainerIpAddresses)
+	state.addressReason = TargetReasonHeuristic
+	if opts.AddressMode == AddressModeIP || opts.AddressMode == AddressModeHostname {
+		state.addressReason = TargetReasonAddressMode
+	}
+
+	// List containers
+	containers, err := withRetry(ctx, opts, "container list", func(ctx context.Context) ([]container.Summary, error) {
+		return cli.ContainerList(ctx, container.ListOptions{All: opts.IncludeStopped, Filters: containerFilters})
+	})
+	if err != nil {
+		return nil, state, fmt.Errorf("failed to list containers: %v", err)
+	}
+
+	var selected []container.Summary
+	for _, c := range containers {
+		// Skip host container if set
+		if state.hostContainerId != "" && c.ID == state.hostContainerId {
+			continue
+		}
+
+		// Skip Newt's own image and siblings
+		if imageExcluded(c.Image, opts.ExcludeImages) {
+			logger.Debug("Skipping container %s with excluded image %s", shortID(c.ID), c.Image)
+			continue
+		}
+
+		if len(opts.Names) > 0 && !nameMatchesPatterns(summaryName(c), opts.Names) {
+			logger.Debug("Skipping container %s whose name matches none of %s", shortID(c.ID), strings.Join(opts.Names, ", "))
+			continue
+		}
+
+		// Opt-out labels win over every other filter
+		if selector, ok := matchingLabelSelector(c.Labels, opts.ExcludeLabelSelectors); ok {
+			logger.Debug("Skipping container %s with exclusion label %s", shortID(c.ID), selector)
+			continue
+		}
+
+		// Skip containers that can never be targets
+		if opts.RoutableOnly && !summaryRoutable(c) {
+			logger.Debug("Skipping container %s without a routable network", shortID(c.ID))
+			continue
+		}
+
+		if opts.RequirePublishedPorts && !summaryPublishesPorts(c) {
+			logger.Debug("Skipping container %s without published ports", shortID(c.ID))
+			continue
+		}
+
+		selected = append(selected, c)
+	}
+
+	sortSummaries(selected, opts.SortBy)
+	if opts.MinUptime > 0 {
+		// The start time is only known after inspecting, pages are cut after the MinUptime filter
+		return selected, state, nil
+	}
+	return paginate(selected, opts.Offset, opts.Limit), state, nil
+}
+
+// mergeDualStackPorts replaces the :: entry of ports that are also published on
+// 0.0.0.0 by marking the 0.0.0.0 entry as dual stack
+func mergeDualStackPorts(ports []Port) []Port {
+	type portKey struct {
+		private, public int
+		protocol        string
+	}
+	published := func(ip string) map[portKey]bool {
+		keys := make(map[portKey]bool)
+		for _, port := range ports {
+			if port.IP == ip && port.PublicPort != 0 {
+				keys[portKey{port.PrivatePort, port.PublicPort, port.Type}] = true
+			}
+		}
+		return keys
+	}
+	ipv4, ipv6 := published("0.0.0.0"), published("::")
+
+	merged := make([]Port, 0, len(ports))
+	for _, port := range ports {
+		key := portKey{port.PrivatePort, port.PublicPort, port.Type}
+		switch {
+		case port.IP == "::" && ipv4[key]:
+			continue
+		case port.IP == "0.0.0.0" && ipv6[key]:
+			port.DualStack = true
+		}
+		merged = append(merged, port)
+	}
+	return merged
+}
+
+// sortPorts orders ports by private port, protocol, public port and bind IP, so
+// port selection does not depend on the order the daemon returned them in
+func sortPorts(ports []Port) {
+	sort.SliceStable(ports, func(i, j int) bool {
+		a, b := ports[i], ports[j]
+		if a.PrivatePort != b.PrivatePort {
+			return a.PrivatePort < b.PrivatePort
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		if a.PublicPort != b.PublicPort {
+			return a.PublicPort < b.PublicPort
+		}
+		return a.IP < b.IP
+	})
+}
+
+// appendExposedPorts adds the ports of the image's EXPOSE directives that the
+// daemon did not report, flagged as Exposed. Newt reaches them on the container IP.
+func appendExposedPorts(ports []Port, config *container.Config) []Port {
+	var exposed []Port
+	for port := range config.ExposedPorts {
+		known := false
+		for _, existing := range ports {
+			if existing.PrivatePort == port.Int() && existing.Type == port.Proto() {
+				known = true
+				break
+			}
+		}
+		if !known {
+			exposed = append(exposed, Port{PrivatePort: port.Int(), Type: port.Proto(), Exposed: true})
+		}
+	}
+	sortPorts(exposed)
+	return append(ports, exposed...)
+}
+
+// appendLabelPorts adds the TCP ports declared by labels matching portLabels that
+// are not yet part of ports. Invalid label values are logged and ignored.
+func appendLabelPorts(ports []Port, labels map[string]string, portLabels []string, containerId string) []Port {
+	if len(portLabels) == 0 {
+		return ports
+	}
+
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if !labelKeyMatches(key, portLabels) {
+			continue
+		}
+
+		port, err := strconv.Atoi(strings.TrimSpace(labels[key]))
+		if err != nil || port < 1 || port > 65535 {
+			logger.Warn("Ignoring port label %s=%q on container %s: expected a port between 1 and 65535", key, labels[key], containerId)
+			continue
+		}
+
+		known := false
+		for _, existing := range ports {
+			if existing.PrivatePort == port && existing.Type == "tcp" {
+				known = true
+				break
+			}
+		}
+		if !known {
+			ports = append(ports, Port{PrivatePort: port, Type: "tcp"})
+		}
+	}
+	return ports
+}
+
+// labelKeyMatches reports whether the label key matches one of the patterns, which may use * wildcards
+func labelKeyMatches(key string, patterns []string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if matched, err := path.Match(pattern, key); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// startingUp reports whether a running container started less than minUptime ago
+func startingUp(c Container, minUptime time.Duration) bool {
+	if minUptime <= 0 || c.State != container.StateRunning || c.StartedAt.IsZero() {
+		return false
+	}
+	if uptime := clockSince(c.StartedAt); uptime < minUptime {
+		logger.Debug("Skipping container %s which started %s ago", c.ID, uptime.Round(time.Second))
+		return true
+	}
+	return false
+}
+
+// DisableLabelSelector lets a container opt out of discovery with newt.enable=false
+const DisableLabelSelector = "newt.enable=false"
+
+// matchingLabelSelector returns the first selector ("key" or "key=value") the labels match
+func matchingLabelSelector(labels map[string]string, selectors []string) (string, bool) {
+	for _, selector := range trimmedValues(selectors) {
+		key, value, hasValue := strings.Cut(selector, "=")
+		labelValue, ok := labels[key]
+		if ok && (!hasValue || labelValue == value) {
+			return selector, true
+		}
+	}
+	return "", false
+}
+
+// summaryRoutable reports whether a listed container is attached to a routable network
+func summaryRoutable(c container.Summary) bool {
+	if c.NetworkSettings == nil {
+		return false
+	}
+	for networkName := range c.NetworkSettings.Networks {
+		if routableNetwork(networkName) {
+			return true
+		}
+	}
+	return false
+}
+
+// summaryPublishesPorts reports whether a listed container publishes any port on the host
+func summaryPublishesPorts(c container.Summary) bool {
+	for _, port := range c.Ports {
+		if port.PublicPort != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// trimmedValues returns the non-empty values with surrounding whitespace removed
+func trimmedValues(values []string) []string {
+	var trimmed []string
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			trimmed = append(trimmed, value)
+		}
+	}
+	return trimmed
+}
+
+// paginate returns the page of containers starting at offset with at most limit entries
+func paginate[T any](containers []T, offset int, limit int) []T {
+	if offset > 0 {
+		if offset >= len(containers) {
+			return nil
+		}
+		containers = containers[offset:]
+	}
+	if limit > 0 && limit < len(containers) {
+		containers = containers[:limit]
+	}
+	return containers
+}
+
+// buildContainer converts a container summary and its optional inspect result into a Container
+func (d *Client) buildContainer(ctx context.Context, c container.Summary, containerInfo *container.InspectResponse, state listState, opts ListOptions) Container {
+	// Short ID like docker ps
+	shortId := shortID(c.ID)
+
+	// Use the inspect result to get hostname, health, restart, command and mount details
+	hostname := ""
+	health := ""
+	restartCount := 0
+	oomKilled := false
+	var cpuQuota, cpuShares, memoryLimit int64
+	var startedAt time.Time
+	command := c.Command
+	var args []string
+	var mounts []Mount
+	var env map[string]string
+	if containerInfo != nil {
+		if containerInfo.Config != nil {
+			hostname = containerInfo.Config.Hostname
+			env = allowedEnv(containerInfo.Config.Env, opts.EnvPrefix)
+
+			// Without an entrypoint the first element of Cmd is the executable
+			command = strings.Join(containerInfo.Config.Entrypoint, " ")
+			args = containerInfo.Config.Cmd
+			if command == "" && len(args) > 0 {
+				command, args = args[0], args[1:]
+			}
+		}
+		if containerInfo.State != nil {
+			oomKilled = containerInfo.State.OOMKilled
+			if t, err := time.Parse(time.RFC3339Nano, containerInfo.State.StartedAt); err == nil && t.Unix() > 0 {
+				startedAt = t
+			}
+			if containerInfo.State.Health != nil {
+				health = containerInfo.State.Health.Status
+			}
+		}
+		restartCount = containerInfo.RestartCount
+		if containerInfo.HostConfig != nil {
+			cpuQuota = containerInfo.HostConfig.CPUQuota
+			cpuShares = containerInfo.HostConfig.CPUShares
+			memoryLimit = containerInfo.HostConfig.Memory
+		}
+
+		for _, mount := range containerInfo.Mounts {
+			mounts = append(mounts, Mount{
+				Type:        string(mount.Type),
+				Source:      mount.Source,
+				Destination: mount.Destination,
+				Mode:        mount.Mode,
+				RW:          mount.RW,
+			})
+		}
+	}
+
+	// Get container name (remove leading slash)
+	name := ""
+	if len(c.Names) > 0 {
+		name = strings.TrimPrefix(c.Names[0], "/")
+	}
+
+	// Convert ports
+	var ports []Port
+	for _, port := range c.Ports {
+		dockerPort := Port{
+			PrivatePort: int(port.PrivatePort),
+			Type:        port.Type,
+		}
+		if port.PublicPort != 0 {
+			dockerPort.PublicPort = int(port.PublicPort)
+		}
+		if port.IP != "" {
+			dockerPort.IP = port.IP
+		}
+		ports = append(ports, dockerPort)
+	}
+	// The daemon builds the list from a map, so its order changes between calls
+	sortPorts(ports)
+	if opts.MergeDualStackPorts {
+		ports = mergeDualStackPorts(ports)
+	}
+
+	// Containers sharing the host network stack serve their exposed ports on the host
+	isHostNetwork := container.NetworkMode(c.HostConfig.NetworkMode).IsHost()
+	if isHostNetwork && containerInfo != nil && containerInfo.Config != nil {
+		exposed := make([]Port, 0, len(containerInfo.Config.ExposedPorts))
+		for port := range containerInfo.Config.ExposedPorts {
+			exposed = append(exposed, Port{PrivatePort: port.Int(), PublicPort: port.Int(), Type: port.Proto()})
+		}
+		sort.Slice(exposed, func(i, j int) bool {
+			if exposed[i].PrivatePort != exposed[j].PrivatePort {
+				return exposed[i].PrivatePort < exposed[j].PrivatePort
+			}
+			return exposed[i].Type < exposed[j].Type
+		})
+		ports = append(ports, exposed...)
+	} else if containerInfo != nil && containerInfo.Config != nil {
+		ports = appendExposedPorts(ports, containerInfo.Config)
+	}
+	ports = appendLabelPorts(ports, c.Labels, opts.PortLabels, shortId)
+	ports, err := removeExcludedPorts(ports, opts.ExcludePorts, c.Labels)
+	if err != nil {
+		logger.Warn("Ignoring label on container %s: %v", shortId, err)
+	}
+
+	// Get network information by inspecting the container
+	networks := make(map[string]Network)
+
+	// Extract network information from inspection
+	if c.NetworkSettings != nil && c.NetworkSettings.Networks != nil {
+		for networkName, endpoint := range c.NetworkSettings.Networks {
+			details := d.networkDetails(ctx, opts, state.networks, endpoint.NetworkID)
+			dockerNetwork := Network{
+				NetworkID:           endpoint.NetworkID,
+				EndpointID:          endpoint.EndpointID,
+				Gateway:             endpoint.Gateway,
+				IPAddress:           endpoint.IPAddress,
+				IPPrefixLen:         endpoint.IPPrefixLen,
+				IPv6Gateway:         endpoint.IPv6Gateway,
+				GlobalIPv6Address:   endpoint.GlobalIPv6Address,
+				GlobalIPv6PrefixLen: endpoint.GlobalIPv6PrefixLen,
+				MacAddress:          endpoint.MacAddress,
+				Aliases:             endpoint.Aliases,
+				DNSNames:            endpoint.DNSNames,
+				Subnet:              details.subnet(endpoint.IPAddress),
+				Internal:            details.internal,
+			}
+
+			networks[networkName] = dockerNetwork
+		}
+	}
+
+	// Explicit labels override the configured address mode and the heuristic
+	targetAddress := selectTargetAddress(networks, hostname, name, state.useContainerIpAddresses, opts.AddressFamily, opts.PreferUserNetworks)
+	targetReason := state.addressReason
+	if newtNetwork, ok := networks[opts.NewtNetwork]; ok && state.useContainerIpAddresses {
+		// Newt reaches the container on its own network, whatever else it is attached to
+		if ip, err := ipAddressOfFamily(map[string]Network{opts.NewtNetwork: newtNetwork}, opts.AddressFamily, false); err == nil {
+			targetAddress = ip
+		}
+	}
+	address, err := labelTargetAddress(c.Labels)
+	if err != nil {
+		logger.Warn("Ignoring label on container %s: %v", shortId, err)
+	}
+	if address != "" {
+		targetAddress = address
+		targetReason = TargetReasonLabel
+	} else if _, ok := c.Labels[TargetNameLabel]; ok {
+		targetAddress = name
+		targetReason = TargetReasonLabel
+		templated := Container{Name: name, Labels: c.Labels, Networks: networks, ComposeProject: c.Labels[ComposeProjectLabel], ComposeService: c.Labels[ComposeServiceLabel]}
+		if rendered, err := labelTargetName(templated); err != nil {
+			logger.Warn("Using container name as target for container %s: %v", shortId, err)
+		} else {
+			targetAddress = rendered
+		}
+	}
+	targetPort, err := labelTargetPort(c.Labels)
+	if err != nil {
+		logger.Warn("Ignoring label on container %s: %v", shortId, err)
+	}
+	targetTTL, err := labelTargetTTL(c.Labels)
+	if err != nil {
+		logger.Warn("Ignoring label on container %s: %v", shortId, err)
+	}
+
+	// Labels choose their own address, otherwise the hostname stands in for a missing IP
+	addressError := ""
+	if state.useContainerIpAddresses && !isHostNetwork && len(networks) > 0 && targetReason != TargetReasonLabel {
+		if _, err := ipAddressOfFamily(networks, opts.AddressFamily, opts.PreferUserNetworks); err != nil {
+			addressError = err.Error()
+			warnNoAddressOfFamily(c.ID, err)
+		}
+	}
+
+	return Container{
+		ID:       shortId,
+		fullID:   c.ID,
+		Name:     name,
+		Image:    c.Image,
+		State:    c.State,
+		Status:   c.Status,
+		Ports:    ports,
+		Labels:   allowedLabels(c.Labels, opts.LabelKeys),
+		Created:  c.Created,
+		Networks: networks,
+		Hostname: hostname, // added
+		Health:   health,
+
+		TargetAddress: targetAddress,
+		TargetReason:  targetReason,
+		TargetPort:    targetPort,
+		TargetTTL:     targetTTL,
+
+		RestartCount: restartCount,
+		OOMKilled:    oomKilled,
+
+		ComposeProject: c.Labels[ComposeProjectLabel],
+		ComposeService: c.Labels[ComposeServiceLabel],
+
+		Mounts: mounts,
+
+		Command: command,
+		Args:    args,
+
+		CPUQuota:    cpuQuota,
+		CPUShares:   cpuShares,
+		MemoryLimit: memoryLimit,
+
+		StartedAt: startedAt,
+
+		IsHostNetwork: isHostNetwork,
+
+		SourceSocket: d.socketPath,
+
+		Env: env,
+
+		AddressError: addressError,
+	}
+}
+
+// addressFamilyWarnings holds the IDs of the containers whose missing address
+// family was already logged, so relisting does not repeat the warning
+var addressFamilyWarnings sync.Map
+
+// warnNoAddressOfFamily logs once per container that its hostname is used instead of an IP
+func warnNoAddressOfFamily(containerID string, err error) {
+	if _, warned := addressFamilyWarnings.LoadOrStore(containerID, struct{}{}); !warned {
+		logger.Warn("Container %s has %v, using its hostname as target", shortID(containerID), err)
+	}
+}
+
+// shortID returns the 12 character ID docker ps shows, or the whole ID if it is shorter
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
+// allowedLabels returns the labels whose key matches one of patterns, or all
+// labels when no pattern is configured
+func allowedLabels(labels map[string]string, patterns []string) map[string]string {
+	if len(patterns) == 0 {
+		return labels
+	}
+	var allowed map[string]string
+	for key, value := range labels {
+		if !labelKeyMatches(key, patterns) {
+			continue
+		}
+		if allowed == nil {
+			allowed = make(map[string]string)
+		}
+		allowed[key] = value
+	}
+	return allowed
+}
+
+// allowedEnv returns the KEY=value entries whose key starts with prefix. An empty
+// prefix exposes nothing.
+func allowedEnv(entries []string, prefix string) map[string]string {
+	if prefix == "" {
+		return nil
+	}
+	var env map[string]string
+	for _, entry := range entries {
+		key, value, _ := strings.Cut(entry, "=")
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if env == nil {
+			env = make(map[string]string)
+		}
+		env[key] = value
+	}
+	return env
+}
+
+// selectTargetAddress picks the container IP of the address family when IP addresses
+// are used, falling back to the hostname or container name when no IP is available
+func selectTargetAddress(networks map[string]Network, hostname string, name string, useIpAddresses bool, family AddressFamily, preferUserNetworks bool) string {
+	if useIpAddresses {
+		if ip, err := ipAddressOfFamily(networks, family, preferUserNetworks); err == nil {
+			return ip
+		}
+	}
+	if hostname != "" {
+		return hostname
+	}
+	return name
+}
+
+// imageExcluded reports whether the image matches one of the excluded image prefixes
+func imageExcluded(image string, excluded []string) bool {
+	normalized := image
+	for _, registry := range []string{"docker.io/", "index.docker.io/", "registry-1.docker.io/"} {
+		normalized = strings.TrimPrefix(normalized, registry)
+	}
+	normalized = strings.TrimPrefix(normalized, "library/")
+
+	for _, prefix := range excluded {
+		prefix = strings.TrimSpace(prefix)
+		if prefix == "" {
+			continue
+		}
+		if strings.HasPrefix(image, prefix) || strings.HasPrefix(normalized, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// inspectContainers inspects the given containers using a bounded pool of workers.
+// The returned slice is indexed like containers; entries are nil when the inspect
+// failed or the container was skipped, so callers degrade to list-only data.
+// Failures are returned joined with ErrPartialResults. Denied inspects are not
+// failures, as discovery deliberately degrades to list-only data then.
+func (d *Client) inspectContainers(ctx context.Context, containers []container.Summary, skipId string, opts ListOptions) ([]*container.InspectResponse, error) {
+	results := make([]*container.InspectResponse, len(containers))
+	if opts.SkipInspect {
+		return results, nil
+	}
+	errs := make([]error, len(containers))
+
+	sem := make(chan struct{}, opts.concurrency())
+	var wg sync.WaitGroup
+
+	// Once the API denies an inspect, the remaining ones would be denied as well
+	var forbidden atomic.Bool
+
+	for i, c := range containers {
+		if skipId != "" && c.ID == skipId {
+			continue
+		}
+		if forbidden.Load() {
+			break
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, id string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			info, err := withRetry(ctx, opts, "container inspect", func(ctx context.Context) (container.InspectResponse, error) {
+				return d.cli.ContainerInspect(ctx, id)
+			})
+			if cerrdefs.IsPermissionDenied(err) {
+				forbidden.Store(true)
+				warnInspectForbidden()
+			} else if err != nil {
+				errs[i] = fmt.Errorf("container %s: %w", shortID(id), err)
+			}
+			if err != nil {
+				logger.Debug("Failed to inspect container %s: %v", id, err)
+				metrics().InspectFailed(d.socketPath, err)
+				return
+			}
+			results[i] = &info
+		}(i, c.ID)
+	}
+
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return results, errors.Join(ErrPartialResults, err)
+	}
+	return results, nil
+}
+
+// getHostContainer gets the current container for the current host if possible. The
+// hostname is tried first, then the container ID from the cgroup and mount paths.
+func getHostContainer(dockerContext context.Context, dockerClient API) (*container.InspectResponse, error) {
+	var candidates []string
+
+	// Get hostname from the os, it is the short container ID unless customized
+	if hostname, err := os.Hostname(); err == nil && hostname != "" {
+		candidates = append(candidates, hostname)
+	}
+	if id := selfContainerID(); id != "" {
+		candidates = append(candidates, id)
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("%w: failed to find hostname or container ID", ErrHostContainerNotFound)
+	}
+
+	// Get host container from the docker socket
+	for _, hostContainerName := range candidates {
+		hostContainer, err := dockerClient.ContainerInspect(dockerContext, hostContainerName)
+		if cerrdefs.IsNotFound(err) {
+			continue
+		}
+		if cerrdefs.IsPermissionDenied(err) {
+			return nil, fmt.Errorf("%w: %w", ErrInspectForbidden, err)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to inspect host container %s: %w", hostContainerName, err)
+		}
+		return &hostContainer, nil
 	}
 
-	return &hostContainer, nil
+	return nil, fmt.Errorf("%w: no container named %s", ErrHostContainerNotFound, strings.Join(candidates, " or "))
 }
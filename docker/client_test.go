@@ -0,0 +1,232 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/fosrl/newt/logger"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func TestMain(m *testing.M) {
+	// Discovery logs every skipped container, keep the test output readable
+	logger.GetLogger().SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// goldenContainer is a representative container covering networks, ports and labels
+func goldenContainer() Container {
+	return Container{
+		ID:     "0123456789ab",
+		Name:   "web-1",
+		Image:  "nginx:1.27",
+		State:  "running",
+		Status: "Up 2 hours",
+		Ports: []Port{
+			{PrivatePort: 80, PublicPort: 8080, Type: "tcp", IP: "0.0.0.0"},
+			{PrivatePort: 443, Type: "tcp"},
+			{PrivatePort: 9000, Type: "tcp", Exposed: true},
+		},
+		Labels: map[string]string{
+			ComposeProjectLabel: "shop",
+			ComposeServiceLabel: "web",
+			TargetPortLabel:     "80",
+		},
+		Created: 1700000000,
+		Networks: map[string]Network{
+			"shop_default": {
+				NetworkID:   "net0123456789",
+				EndpointID:  "ep0123456789",
+				Gateway:     "172.20.0.1",
+				IPAddress:   "172.20.0.5",
+				IPPrefixLen: 16,
+				MacAddress:  "02:42:ac:14:00:05",
+				Aliases:     []string{"web"},
+				DNSNames:    []string{"web-1", "web"},
+			},
+		},
+		Hostname:       "0123456789ab",
+		Health:         "healthy",
+		TargetAddress:  "172.20.0.5",
+		TargetReason:   TargetReasonHeuristic,
+		TargetPort:     80,
+		RestartCount:   1,
+		ComposeProject: "shop",
+		ComposeService: "web",
+		StartedAt:      time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		SourceSocket:   "unix:///var/run/docker.sock",
+	}
+}
+
+func TestContainerJSONGolden(t *testing.T) {
+	got, err := json.MarshalIndent(goldenContainer(), "", "  ")
+	if err != nil {
+		t.Fatalf("marshal container: %v", err)
+	}
+	got = append(got, '\n')
+
+	golden := filepath.Join("testdata", "container.golden.json")
+	if *update {
+		if err := os.WriteFile(golden, got, 0o644); err != nil {
+			t.Fatalf("update golden file: %v", err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("read golden file: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("container JSON changed, the wire format is part of the Pangolin API\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestMinUptimeAppliesBeforePagination(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	SetClock(func() time.Time { return now })
+	defer SetClock(nil)
+
+	api := &fakeAPI{}
+	for i, uptime := range []time.Duration{time.Second, 2 * time.Second, time.Hour, 2 * time.Hour} {
+		c := fakeContainer(fmt.Sprintf("%012d", i+1), fmt.Sprintf("web-%d", i+1), "shop", fmt.Sprintf("172.20.0.%d", i+2))
+		api.summaries = append(api.summaries, c)
+		api.startedAt(c, now.Add(-uptime))
+	}
+	d := newFakeClient(t, api)
+	opts := ListOptions{MinUptime: time.Minute, SortBy: SortByName}
+
+	tests := []struct {
+		offset, limit int
+		want          []string
+	}{
+		{0, 1, []string{"web-3"}},
+		{1, 1, []string{"web-4"}},
+		{0, 0, []string{"web-3", "web-4"}},
+		{2, 1, nil},
+	}
+	for _, tt := range tests {
+		opts.Offset, opts.Limit = tt.offset, tt.limit
+
+		containers, err := d.ListContainers(context.Background(), false, opts)
+		if err != nil {
+			t.Fatalf("ListContainers: %v", err)
+		}
+		var names []string
+		for _, c := range containers {
+			names = append(names, c.Name)
+		}
+		if !slices.Equal(names, tt.want) {
+			t.Errorf("ListContainers offset %d limit %d = %v, want %v", tt.offset, tt.limit, names, tt.want)
+		}
+
+		names = nil
+		err = d.ForEachContainer(context.Background(), false, opts, func(c Container) error {
+			names = append(names, c.Name)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("ForEachContainer: %v", err)
+		}
+		if !slices.Equal(names, tt.want) {
+			t.Errorf("ForEachContainer offset %d limit %d = %v, want %v", tt.offset, tt.limit, names, tt.want)
+		}
+	}
+}
+
+func TestNetworkHasIP(t *testing.T) {
+	network := Network{
+		IPAddress:         "172.18.0.2",
+		GlobalIPv6Address: "fd00:0:0:0:0:0:0:2",
+	}
+
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"172.18.0.2", true},
+		{"172.18.0.3", false},
+		{"fd00::2", true},
+		{"fd00:0000:0000:0000:0000:0000:0000:0002", true},
+		{"FD00::2", true},
+		{"fd00::3", false},
+		{"::ffff:172.18.0.2", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			if got := networkHasIP(network, net.ParseIP(tt.ip)); got != tt.want {
+				t.Errorf("networkHasIP(%s) = %t, want %t", tt.ip, got, tt.want)
+			}
+		})
+	}
+
+	compressed := Network{GlobalIPv6Address: "fd00::2"}
+	if !networkHasIP(compressed, net.ParseIP("fd00:0:0:0:0:0:0:2")) {
+		t.Errorf("the expanded form does not match a compressed endpoint address")
+	}
+	if networkHasIP(Network{}, net.ParseIP("::")) {
+		t.Errorf("an endpoint without addresses matches the unspecified address")
+	}
+}
+
+func TestSelectTargetAddress(t *testing.T) {
+	networks := map[string]Network{
+		"app": {IPAddress: "172.18.0.2", GlobalIPv6Address: "fd00::2"},
+	}
+
+	tests := []struct {
+		name     string
+		networks map[string]Network
+		hostname string
+		useIP    bool
+		family   AddressFamily
+		want     string
+	}{
+		{"ip address", networks, "web", true, AddressFamilyIPv4, "172.18.0.2"},
+		{"ipv6 address", networks, "web", true, AddressFamilyIPv6, "fd00::2"},
+		{"hostname", networks, "web", false, AddressFamilyIPv4, "web"},
+		{"name without hostname", networks, "", false, AddressFamilyIPv4, "web-1"},
+		{"no address of the family", map[string]Network{"app": {IPAddress: "172.18.0.2"}}, "web", true, AddressFamilyIPv6, "web"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := selectTargetAddress(tt.networks, tt.hostname, "web-1", tt.useIP, tt.family, false); got != tt.want {
+				t.Errorf("selectTargetAddress = %q, want %q", got, tt.want)
+			}
+			// Selecting the target must never change the networks it reads
+			if tt.networks["app"].IPAddress != "172.18.0.2" {
+				t.Errorf("IPAddress changed to %q", tt.networks["app"].IPAddress)
+			}
+		})
+	}
+}
+
+func TestListContainersKeepsIPAddress(t *testing.T) {
+	for _, mode := range []AddressMode{AddressModeAuto, AddressModeIP, AddressModeHostname} {
+		t.Run(string(mode), func(t *testing.T) {
+			api := &fakeAPI{summaries: []container.Summary{
+				fakeContainer("a1b2c3d4e5f6", "web", "app", "172.18.0.2"),
+			}}
+			containers, err := newFakeClient(t, api).ListContainers(context.Background(), false, ListOptions{AddressMode: mode})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(containers) != 1 {
+				t.Fatalf("listed %d containers, want 1", len(containers))
+			}
+			if got := containers[0].Networks["app"].IPAddress; got != "172.18.0.2" {
+				t.Errorf("IPAddress = %q, want 172.18.0.2", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package docker
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// clockHolder wraps the function so atomic.Value always stores one concrete type
+type clockHolder struct {
+	now func() time.Time
+}
+
+var currentClock atomic.Value
+
+// SetClock replaces the source of the current time used for cache expiry,
+// MinUptime filtering and listing durations, so tests can control time
+// without sleeping. Passing nil restores time.Now.
+func SetClock(now func() time.Time) {
+	if now == nil {
+		now = time.Now
+	}
+	currentClock.Store(clockHolder{now})
+}
+
+// clockNow returns the current time of the installed clock
+func clockNow() time.Time {
+	if holder, ok := currentClock.Load().(clockHolder); ok {
+		return holder.now()
+	}
+	return time.Now()
+}
+
+// clockSince is time.Since using the installed clock
+func clockSince(t time.Time) time.Duration {
+	return clockNow().Sub(t)
+}
@@ -0,0 +1,34 @@
+package docker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+func TestStartingUp(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	SetClock(func() time.Time { return now })
+	defer SetClock(nil)
+
+	tests := []struct {
+		name      string
+		c         Container
+		minUptime time.Duration
+		want      bool
+	}{
+		{"started recently", Container{State: container.StateRunning, StartedAt: now.Add(-10 * time.Second)}, time.Minute, true},
+		{"started long enough ago", Container{State: container.StateRunning, StartedAt: now.Add(-time.Minute)}, time.Minute, false},
+		{"no minimum uptime", Container{State: container.StateRunning, StartedAt: now}, 0, false},
+		{"unknown start time", Container{State: container.StateRunning}, time.Minute, false},
+		{"not running", Container{State: container.StateExited, StartedAt: now}, time.Minute, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := startingUp(tt.c, tt.minUptime); got != tt.want {
+				t.Errorf("startingUp = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
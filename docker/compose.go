@@ -0,0 +1,17 @@
+package docker
+
+// Labels set by Docker Compose on every container it manages
+const (
+	ComposeProjectLabel = "com.docker.compose.project"
+	ComposeServiceLabel = "com.docker.compose.service"
+)
+
+// GroupByComposeProject groups containers by their Docker Compose project.
+// Containers that are not managed by Compose are grouped under the empty key.
+func GroupByComposeProject(containers []Container) map[string][]Container {
+	groups := make(map[string][]Container)
+	for _, c := range containers {
+		groups[c.ComposeProject] = append(groups[c.ComposeProject], c)
+	}
+	return groups
+}
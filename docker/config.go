@@ -0,0 +1,129 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Config collects Newt's Docker settings in one place. A larger configuration can
+// embed it instead of passing every option separately, and it maps onto the
+// ClientOptions and ListOptions used by the rest of the package.
+type Config struct {
+	// SocketPath is a socket path or Docker host URI, several separated by commas.
+	// When empty, Context is used, then DOCKER_HOST, DOCKER_CONTEXT and socket detection.
+	SocketPath string
+
+	// Context is a Docker CLI context whose endpoint is used when SocketPath is empty
+	Context string
+
+	// TLS configures client certificates for remote tcp:// daemons
+	TLS *TLSConfig
+
+	// MinAPIVersion rejects older daemons, see ClientOptions.MinAPIVersion
+	MinAPIVersion string
+
+	// EnforceNetworkValidation only discovers containers on Newt's networks
+	EnforceNetworkValidation bool
+
+	// LabelSelectors, ExcludeLabelSelectors, ExcludeImages, PortLabels, Networks
+	// and Names are passed on to the ListOptions fields of the same name
+	LabelSelectors        []string
+	ExcludeLabelSelectors []string
+	ExcludeImages         []string
+	PortLabels            []string
+	Networks              []string
+	Names                 []string
+
+	// NewtNetwork scopes discovery to a network Newt joins, see ListOptions.NewtNetwork
+	NewtNetwork string
+
+	// AddressMode, AddressFamily and PreferUserNetworks control the target
+	// address, see the ListOptions fields of the same name
+	AddressMode        AddressMode
+	AddressFamily      AddressFamily
+	PreferUserNetworks bool
+
+	// ExcludePorts are never advertised or validated, see ListOptions.ExcludePorts
+	ExcludePorts []PortRange
+
+	// EnvPrefix selects the environment variables exposed as Container.Env
+	EnvPrefix string
+
+	// LabelKeys restricts the labels sent with a container, see ListOptions.LabelKeys
+	LabelKeys []string
+}
+
+// SocketPaths returns the configured socket paths, resolving Context when
+// SocketPath is empty. Nil means the default detection applies.
+func (c Config) SocketPaths() ([]string, error) {
+	if c.SocketPath == "" && c.Context != "" {
+		host, err := ContextHost(c.Context)
+		if err != nil {
+			return nil, err
+		}
+		return []string{host}, nil
+	}
+	return SplitSocketPaths(c.SocketPath), nil
+}
+
+// ClientOptions returns the options for the clients of the configured daemons
+func (c Config) ClientOptions() ClientOptions {
+	return ClientOptions{TLS: c.TLS, MinAPIVersion: c.MinAPIVersion}
+}
+
+// ListOptions returns the list options matching the configuration
+func (c Config) ListOptions() ListOptions {
+	return ListOptions{
+		LabelSelectors:        c.LabelSelectors,
+		ExcludeLabelSelectors: c.ExcludeLabelSelectors,
+		ExcludeImages:         c.ExcludeImages,
+		PortLabels:            c.PortLabels,
+		Networks:              c.Networks,
+		Names:                 c.Names,
+		NewtNetwork:           c.NewtNetwork,
+		AddressMode:           c.AddressMode,
+		AddressFamily:         c.AddressFamily,
+		PreferUserNetworks:    c.PreferUserNetworks,
+		ExcludePorts:          c.ExcludePorts,
+		EnvPrefix:             c.EnvPrefix,
+		LabelKeys:             c.LabelKeys,
+	}
+}
+
+// NewClientsFromConfig creates a client for every configured daemon. Daemons whose
+// client can't be created are skipped and their errors returned joined, along with
+// the clients that were created. Logs a warning once when network validation is
+// not enforced.
+func NewClientsFromConfig(cfg Config) ([]*Client, error) {
+	if !cfg.EnforceNetworkValidation {
+		warnValidationDisabled()
+	}
+
+	socketPaths, err := cfg.SocketPaths()
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve Docker context %s: %w", cfg.Context, err)
+	}
+	if len(socketPaths) == 0 {
+		// Fall back to DOCKER_HOST or the detected socket
+		socketPaths = []string{""}
+	}
+
+	var clients []*Client
+	var errs []error
+	for _, socketPath := range socketPaths {
+		dockerClient, err := NewClientWithOptions(socketPath, cfg.ClientOptions())
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", socketPath, err))
+			continue
+		}
+		clients = append(clients, dockerClient)
+	}
+	return clients, errors.Join(errs...)
+}
+
+// ListContainersWithConfig lists the containers of the clients using the
+// configuration, see ListContainersFromClients
+func ListContainersWithConfig(ctx context.Context, clients []*Client, cfg Config) ([]Container, error) {
+	return ListContainersFromClients(ctx, clients, cfg.EnforceNetworkValidation, cfg.ListOptions())
+}
@@ -0,0 +1,142 @@
+package docker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/fosrl/newt/logger"
+)
+
+// defaultContextName is the implicit docker CLI context that uses DOCKER_HOST or the default socket
+const defaultContextName = "default"
+
+// dockerCLIConfig is the subset of the docker CLI config.json we need
+type dockerCLIConfig struct {
+	CurrentContext string `json:"currentContext"`
+}
+
+// contextMeta is the subset of the docker CLI context metadata we need
+type contextMeta struct {
+	Name      string `json:"Name"`
+	Endpoints map[string]struct {
+		Host string `json:"Host"`
+	} `json:"Endpoints"`
+}
+
+// dockerConfigDir returns the docker CLI configuration directory, honoring DOCKER_CONFIG
+func dockerConfigDir() (string, error) {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".docker"), nil
+}
+
+// resolveContextHost returns the Docker endpoint of the named docker CLI context
+func resolveContextHost(name string) (string, error) {
+	configDir, err := dockerConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate docker config directory: %v", err)
+	}
+
+	// Context metadata is stored under the SHA-256 digest of the context name
+	digest := sha256.Sum256([]byte(name))
+	metaPath := filepath.Join(configDir, "contexts", "meta", hex.EncodeToString(digest[:]), "meta.json")
+
+	data, err := os.ReadFile(metaPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read docker context %q: %v", name, err)
+	}
+
+	var meta contextMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return "", fmt.Errorf("failed to parse docker context %q: %v", name, err)
+	}
+
+	endpoint, ok := meta.Endpoints["docker"]
+	if !ok || endpoint.Host == "" {
+		return "", fmt.Errorf("docker context %q has no docker endpoint", name)
+	}
+	return endpoint.Host, nil
+}
+
+// currentContextName returns the active docker CLI context: DOCKER_CONTEXT when
+// set, otherwise the currentContext of the docker CLI config. It returns "" when
+// no context is selected.
+func currentContextName() string {
+	if name := os.Getenv("DOCKER_CONTEXT"); name != "" {
+		return name
+	}
+
+	configDir, err := dockerConfigDir()
+	if err != nil {
+		return ""
+	}
+	data, err := os.ReadFile(filepath.Join(configDir, "config.json"))
+	if err != nil {
+		return ""
+	}
+	var config dockerCLIConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		logger.Debug("Failed to parse docker CLI config: %v", err)
+		return ""
+	}
+	return config.CurrentContext
+}
+
+// ContextHost returns the Docker endpoint of a docker CLI context, as listed by
+// `docker context ls`. An empty name selects the active context. The default
+// context resolves to DOCKER_HOST or the default socket. When the context
+// cannot be resolved the default socket is returned along with the error.
+func ContextHost(name string) (string, error) {
+	if name == "" {
+		name = currentContextName()
+	}
+
+	if name == "" || name == defaultContextName {
+		if host := os.Getenv("DOCKER_HOST"); host != "" {
+			return host, nil
+		}
+		return DefaultSocketPath, nil
+	}
+
+	host, err := resolveContextHost(name)
+	if err != nil {
+		return DefaultSocketPath, err
+	}
+	return host, nil
+}
+
+// contextWarnings holds the names of the docker contexts whose resolution
+// failure was already logged, so creating further clients does not repeat it
+var contextWarnings sync.Map
+
+// hostFromEnvironment returns the Docker host selected by the environment, matching
+// the docker CLI: DOCKER_HOST wins over DOCKER_CONTEXT and the active context of
+// the docker CLI config. It returns "" when none of them selects a daemon.
+func hostFromEnvironment() string {
+	if host := os.Getenv("DOCKER_HOST"); host != "" {
+		return host
+	}
+
+	if name := currentContextName(); name != "" && name != defaultContextName {
+		host, err := resolveContextHost(name)
+		if err != nil {
+			if _, warned := contextWarnings.LoadOrStore(name, struct{}{}); !warned {
+				logger.WithFields(logger.Fields{"context": name}).Warn("Failed to resolve docker context, falling back to the default socket: %v", err)
+			}
+			return ""
+		}
+		return host
+	}
+
+	return ""
+}
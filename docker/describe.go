@@ -0,0 +1,105 @@
+package docker
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// DescribeContainers writes a human readable table of the containers and the
+// address Newt would advertise for each of them, sorted by container name. It
+// does not register anything and is meant for checking discovery before going live.
+func DescribeContainers(w io.Writer, containers []Container) error {
+	sorted := make([]Container, len(containers))
+	copy(sorted, containers)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Name != sorted[j].Name {
+			return sorted[i].Name < sorted[j].Name
+		}
+		return sorted[i].ID < sorted[j].ID
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tIMAGE\tNETWORKS\tPORTS\tADDRESS")
+	for _, c := range sorted {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+			c.Name,
+			c.Image,
+			valueOrDash(strings.Join(sortedNetworkNames(c), ",")),
+			valueOrDash(describePorts(c.Ports)),
+			valueOrDash(describeTargetAddress(c)),
+		)
+	}
+	return tw.Flush()
+}
+
+// describeTargetAddress returns the resolved target address or the resolution error
+func describeTargetAddress(c Container) string {
+	address, _, err := (AddressResolver{}).resolveTargetAddress(c)
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	return address
+}
+
+// sortedNetworkNames returns the container's network names in a stable order
+func sortedNetworkNames(c Container) []string {
+	names := make([]string, 0, len(c.Networks))
+	for name := range c.Networks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// preferredNetworkNames returns the container's network names with user defined,
+// non-internal networks first. Both groups are in name order.
+func preferredNetworkNames(c Container) []string {
+	var preferred, others []string
+	for _, name := range sortedNetworkNames(c) {
+		if userNetwork(name, c.Networks[name]) {
+			preferred = append(preferred, name)
+		} else {
+			others = append(others, name)
+		}
+	}
+	return append(preferred, others...)
+}
+
+// userNetwork reports whether the network is user defined and not internal
+func userNetwork(name string, network Network) bool {
+	switch name {
+	case "bridge", "host", "none":
+		return false
+	}
+	return !network.Internal
+}
+
+// describePorts renders ports like docker ps, e.g. 0.0.0.0:8080->80/tcp
+func describePorts(ports []Port) string {
+	parts := make([]string, 0, len(ports))
+	for _, port := range ports {
+		part := fmt.Sprintf("%d/%s", port.PrivatePort, port.Type)
+		if port.PublicPort != 0 {
+			host := strconv.Itoa(port.PublicPort)
+			if port.IP != "" {
+				host = net.JoinHostPort(port.IP, host)
+			}
+			part = host + "->" + part
+		}
+		parts = append(parts, part)
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ", ")
+}
+
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
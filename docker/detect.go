@@ -0,0 +1,66 @@
+package docker
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/fosrl/newt/logger"
+)
+
+// AutoSocketPath requests detection of the container runtime socket
+const AutoSocketPath = "auto"
+
+// socketCandidate is a well known container runtime socket location
+type socketCandidate struct {
+	runtime string // e.g. docker, rootless docker, podman
+	path    string // unix socket path
+}
+
+var (
+	detectedMu     sync.Mutex
+	detectedSocket string
+)
+
+// socketCandidates returns the socket locations probed during detection, in order.
+// Rootless Docker sockets come first, as a rootless user can't use the rootful one.
+func socketCandidates() []socketCandidate {
+	var candidates []socketCandidate
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		candidates = append(candidates, socketCandidate{"rootless docker", filepath.Join(runtimeDir, "docker.sock")})
+	}
+	return append(candidates,
+		socketCandidate{"rootless docker", fmt.Sprintf("/run/user/%d/docker.sock", os.Getuid())},
+		socketCandidate{"docker", strings.TrimPrefix(DefaultSocketPath, "unix://")},
+		socketCandidate{"podman", fmt.Sprintf("/run/user/%d/podman/podman.sock", os.Getuid())},
+		socketCandidate{"podman", "/run/podman/podman.sock"},
+	)
+}
+
+// detectSocket returns the first existing runtime socket, falling back to the
+// default Docker socket when none is found. Podman exposes a Docker compatible
+// API so its socket can be used as is.
+func detectSocket() string {
+	selected := DefaultSocketPath
+	runtime := "docker"
+
+	for _, candidate := range socketCandidates() {
+		if _, err := os.Stat(candidate.path); err == nil {
+			selected = "unix://" + candidate.path
+			runtime = candidate.runtime
+			break
+		}
+	}
+
+	// Only log when the detected socket changes to avoid spamming on every call
+	detectedMu.Lock()
+	defer detectedMu.Unlock()
+	if selected != detectedSocket {
+		logger.WithFields(logger.Fields{"socketPath": selected, "runtime": runtime}).Info("Detected %s socket", runtime)
+		detectedSocket = selected
+	}
+
+	return selected
+}
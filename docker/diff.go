@@ -0,0 +1,70 @@
+package docker
+
+import (
+	"maps"
+	"slices"
+)
+
+// ContainerDiff describes how the containers changed between two discovery runs
+type ContainerDiff struct {
+	Added   []Container // containers only in the new snapshot
+	Removed []Container // containers only in the old snapshot
+	Changed []Container // new version of containers whose target relevant details differ
+}
+
+// Empty reports whether nothing changed between the snapshots
+func (d ContainerDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// DiffContainers compares two snapshots by container ID (and source socket, as IDs
+// are only unique per daemon) so only the deltas need to be pushed to Pangolin.
+// A container counts as changed when its name, state, target address, ports or
+// network addresses differ. Results keep the order of the snapshots.
+func DiffContainers(old, new []Container) ContainerDiff {
+	oldByKey := make(map[string]Container, len(old))
+	for _, c := range old {
+		oldByKey[diffKey(c)] = c
+	}
+	newKeys := make(map[string]bool, len(new))
+
+	var diff ContainerDiff
+	for _, c := range new {
+		key := diffKey(c)
+		newKeys[key] = true
+
+		previous, ok := oldByKey[key]
+		if !ok {
+			diff.Added = append(diff.Added, c)
+		} else if containerChanged(previous, c) {
+			diff.Changed = append(diff.Changed, c)
+		}
+	}
+
+	for _, c := range old {
+		if !newKeys[diffKey(c)] {
+			diff.Removed = append(diff.Removed, c)
+		}
+	}
+
+	return diff
+}
+
+// diffKey identifies a container across snapshots
+func diffKey(c Container) string {
+	return c.SourceSocket + "/" + c.ID
+}
+
+// containerChanged reports whether details that affect targets differ
+func containerChanged(a, b Container) bool {
+	if a.Name != b.Name || a.State != b.State || a.TargetAddress != b.TargetAddress || a.TargetPort != b.TargetPort {
+		return true
+	}
+	if !slices.Equal(a.Ports, b.Ports) || !maps.Equal(a.Env, b.Env) {
+		return true
+	}
+	return !maps.EqualFunc(a.Networks, b.Networks, func(x, y Network) bool {
+		return x.IPAddress == y.IPAddress && x.GlobalIPv6Address == y.GlobalIPv6Address &&
+			slices.Equal(x.Aliases, y.Aliases) && slices.Equal(x.DNSNames, y.DNSNames)
+	})
+}
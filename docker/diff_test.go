@@ -0,0 +1,87 @@
+package docker
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// diffIDs returns the IDs of the containers in order
+func diffIDs(containers []Container) []string {
+	ids := make([]string, len(containers))
+	for i, c := range containers {
+		ids[i] = c.ID
+	}
+	return ids
+}
+
+func TestDiffContainers(t *testing.T) {
+	base := Container{
+		ID:            "a1b2c3d4e5f6",
+		Name:          "web",
+		State:         container.StateRunning,
+		TargetAddress: "172.18.0.2",
+		SourceSocket:  "unix:///var/run/docker.sock",
+		Ports:         []Port{{PrivatePort: 80, Type: "tcp"}},
+		Networks:      map[string]Network{"app": {IPAddress: "172.18.0.2"}},
+	}
+
+	tests := []struct {
+		name    string
+		change  func(c *Container)
+		changed bool
+	}{
+		{"unchanged", func(c *Container) {}, false},
+		{"renamed", func(c *Container) { c.Name = "web-new" }, true},
+		{"port added", func(c *Container) { c.Ports = append(slices.Clone(c.Ports), Port{PrivatePort: 443, Type: "tcp"}) }, true},
+		{"port removed", func(c *Container) { c.Ports = nil }, true},
+		{"port protocol changed", func(c *Container) { c.Ports = []Port{{PrivatePort: 80, Type: "udp"}} }, true},
+		{"stopped", func(c *Container) { c.State = container.StateExited }, true},
+		{"paused", func(c *Container) { c.State = container.StatePaused }, true},
+		{"address changed", func(c *Container) { c.Networks = map[string]Network{"app": {IPAddress: "172.18.0.9"}} }, true},
+		{"status text changed", func(c *Container) { c.Status = "Up 2 minutes" }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated := base
+			tt.change(&updated)
+
+			diff := DiffContainers([]Container{base}, []Container{updated})
+			if len(diff.Added) != 0 || len(diff.Removed) != 0 {
+				t.Errorf("container was added %v or removed %v, want it kept", diffIDs(diff.Added), diffIDs(diff.Removed))
+			}
+			if got := len(diff.Changed) == 1; got != tt.changed {
+				t.Errorf("changed = %t, want %t", got, tt.changed)
+			}
+			if diff.Empty() == tt.changed {
+				t.Errorf("Empty() = %t, want %t", diff.Empty(), !tt.changed)
+			}
+		})
+	}
+}
+
+func TestDiffContainersAddedAndRemoved(t *testing.T) {
+	web := Container{ID: "a1b2c3d4e5f6", Name: "web", SourceSocket: "unix:///var/run/docker.sock"}
+	api := Container{ID: "b1c2d3e4f5a6", Name: "api", SourceSocket: "unix:///var/run/docker.sock"}
+	db := Container{ID: "c1d2e3f4a5b6", Name: "db", SourceSocket: "unix:///var/run/docker.sock"}
+
+	diff := DiffContainers([]Container{web, api}, []Container{api, db})
+	if got := diffIDs(diff.Added); !slices.Equal(got, []string{db.ID}) {
+		t.Errorf("Added = %v, want [%s]", got, db.ID)
+	}
+	if got := diffIDs(diff.Removed); !slices.Equal(got, []string{web.ID}) {
+		t.Errorf("Removed = %v, want [%s]", got, web.ID)
+	}
+	if len(diff.Changed) != 0 {
+		t.Errorf("Changed = %v, want none", diffIDs(diff.Changed))
+	}
+
+	// IDs are only unique per daemon, the same ID on another socket is another container
+	remote := web
+	remote.SourceSocket = "tcp://remote:2376"
+	diff = DiffContainers([]Container{web}, []Container{remote})
+	if len(diff.Added) != 1 || len(diff.Removed) != 1 {
+		t.Errorf("moving to another socket added %d and removed %d, want 1 each", len(diff.Added), len(diff.Removed))
+	}
+}
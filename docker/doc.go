@@ -0,0 +1,20 @@
+// Package docker discovers containers through the Docker API so Newt can offer
+// them as Pangolin targets and validate targets against Newt's networks.
+//
+// Concurrency: every exported function and every Client method is safe to call
+// from multiple goroutines. A Client may be shared; the underlying Docker client
+// is goroutine-safe and the version logging is guarded by a sync.Once. Shared
+// package state is guarded as follows:
+//
+//   - the container cache (see InvalidateCache) by a sync.RWMutex; listings are
+//     deep-copied in and out of it, so callers may modify returned containers
+//   - the detected socket (see AutoSocketPath) by a sync.Mutex
+//   - the metrics hook (see SetMetrics) by an atomic.Value
+//   - the clock (see SetClock) by an atomic.Value
+//   - the connection state hook (see SetConnectionStateHook) by an atomic.Value
+//   - the target audit hook (see SetTargetAuditHook) by an atomic.Value
+//   - the one-time warnings by sync.Once, per container ones by a sync.Map
+//
+// Exported variables such as DefaultAddressPreference are read without locking
+// and must only be changed during start up, before discovery runs.
+package docker
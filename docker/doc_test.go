@@ -0,0 +1,71 @@
+package docker
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// countingMetrics counts listings, it is installed while discovery runs
+type countingMetrics struct {
+	noopMetrics
+	lists atomic.Int64
+}
+
+func (m *countingMetrics) ListCompleted(string, time.Duration, int, error) { m.lists.Add(1) }
+
+// TestConcurrentDiscovery lists and matches containers while the hooks are
+// replaced, run it with -race to check the guarantees documented in doc.go
+func TestConcurrentDiscovery(t *testing.T) {
+	defer SetMetrics(nil)
+	defer SetClock(nil)
+	defer SetTargetAuditHook(nil)
+
+	api := &fakeAPI{summaries: []container.Summary{
+		fakeContainer("a1b2c3d4e5f6", "web", "app", "172.18.0.2"),
+		fakeContainer("b1c2d3e4f5a6", "api", "app", "172.18.0.3"),
+	}}
+	api.runsOn(t, "app")
+	dockerClient := newFakeClient(t, api)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				containers, err := dockerClient.ListContainers(ctx, false, ListOptions{ForceRefresh: j%4 == 0})
+				if err != nil {
+					t.Errorf("ListContainers: %v", err)
+					return
+				}
+				// Returned listings are copies, modifying them must not race with the cache
+				for k := range containers {
+					containers[k].Labels["modified"] = "true"
+				}
+				if _, _, err := dockerClient.MatchContainer(ctx, "web", 80); err != nil {
+					t.Errorf("MatchContainer: %v", err)
+					return
+				}
+				if j%5 == 0 {
+					InvalidateCache(dockerClient.SocketPath())
+				}
+			}
+		}(i)
+	}
+
+	for i := 0; i < 20; i++ {
+		SetMetrics(&countingMetrics{})
+		SetClock(func() time.Time { return time.Now().Add(time.Duration(i) * time.Second) })
+		SetTargetAuditHook(func(TargetDecision) {})
+		SetMetrics(nil)
+		SetClock(nil)
+		SetTargetAuditHook(nil)
+	}
+	wg.Wait()
+}
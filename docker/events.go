@@ -0,0 +1,199 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/fosrl/newt/logger"
+)
+
+// WatchContainers waits watchReconnectInterval before reconnecting after the
+// events stream drops, doubling the wait up to watchMaxReconnectInterval while
+// the daemon stays unreachable or the stream keeps dropping without events
+const (
+	watchReconnectInterval    = 3 * time.Second
+	watchMaxReconnectInterval = time.Minute
+)
+
+// ContainerEventResync is the action of the event WatchContainers emits after
+// reconnecting. Events may have been missed while disconnected, so consumers
+// must list the containers again rather than rely on the events so far.
+const ContainerEventResync = "resync"
+
+// ConnectionState is the state of the events subscription of WatchContainers
+type ConnectionState string
+
+const (
+	ConnectionConnected    ConnectionState = "connected"
+	ConnectionDisconnected ConnectionState = "disconnected"
+)
+
+// connectionHolder wraps the hook so atomic.Value always stores one concrete type
+type connectionHolder struct {
+	hook func(socketPath string, state ConnectionState, err error)
+}
+
+var currentConnectionHook atomic.Value
+
+// SetConnectionStateHook installs a hook that is called whenever the events
+// subscription of WatchContainers connects or disconnects, with the error that
+// dropped it. Transitions are logged either way. The hook is called synchronously
+// and must be safe for concurrent use. Passing nil removes it.
+func SetConnectionStateHook(hook func(socketPath string, state ConnectionState, err error)) {
+	currentConnectionHook.Store(connectionHolder{hook})
+}
+
+// reportConnectionState reports a transition to the installed hook
+func reportConnectionState(socketPath string, state ConnectionState, err error) {
+	if holder, ok := currentConnectionHook.Load().(connectionHolder); ok && holder.hook != nil {
+		holder.hook(socketPath, state, err)
+	}
+}
+
+// ContainerEvent describes a container lifecycle change reported by Docker
+type ContainerEvent struct {
+	Action      string    `json:"action"` // start, stop, die, destroy or ContainerEventResync
+	ContainerID string    `json:"containerId"`
+	Name        string    `json:"name"`
+	Image       string    `json:"image"`
+	Time        time.Time `json:"time"`
+}
+
+// WatchContainers subscribes to Docker container start, stop, die and destroy
+// events and emits them on the returned channel. If the events stream drops,
+// e.g. on a daemon restart, the subscription is re-established with backoff and
+// a ContainerEventResync event is emitted once reconnected. Connection state
+// changes are logged and reported to the SetConnectionStateHook hook. The
+// channel is closed once ctx is cancelled. Cached container listings for the
+// socket are invalidated on every event so the next ListContainers call
+// reflects the change.
+func WatchContainers(ctx context.Context, socketPath string, clientOpts ClientOptions, opts ListOptions) (<-chan ContainerEvent, error) {
+	dockerClient, err := NewClientWithOptions(socketPath, clientOpts)
+	if err != nil {
+		return nil, err
+	}
+	return dockerClient.watchContainers(ctx, opts, true), nil
+}
+
+// WatchContainers subscribes to Docker container events, see the package level
+// WatchContainers. The client is left open once ctx is cancelled.
+func (d *Client) WatchContainers(ctx context.Context, opts ListOptions) <-chan ContainerEvent {
+	return d.watchContainers(ctx, opts, false)
+}
+
+// watchContainers runs the events subscription loop, closing the client when done if requested
+func (d *Client) watchContainers(ctx context.Context, opts ListOptions, closeClient bool) <-chan ContainerEvent {
+	socketPath := d.socketPath
+	log := logger.WithFields(logger.Fields{"socketPath": socketPath})
+
+	eventFilters := filters.NewArgs()
+	eventFilters.Add("type", string(events.ContainerEventType))
+	eventFilters.Add("event", string(events.ActionStart))
+	eventFilters.Add("event", string(events.ActionStop))
+	eventFilters.Add("event", string(events.ActionDie))
+	eventFilters.Add("event", string(events.ActionDestroy))
+	for _, selector := range opts.LabelSelectors {
+		if selector != "" {
+			eventFilters.Add("label", selector)
+		}
+	}
+
+	out := make(chan ContainerEvent)
+
+	go func() {
+		defer close(out)
+		if closeClient {
+			defer d.Close()
+		}
+
+		// The backoff carries over between drops so a flapping stream is not
+		// resubscribed at the base interval, events reset it
+		reconnected := false
+		wait := watchReconnectInterval
+		for {
+			messages, errs := d.cli.Events(ctx, events.ListOptions{Filters: eventFilters})
+			log.Debug("Subscribed to Docker events")
+			reportConnectionState(socketPath, ConnectionConnected, nil)
+
+			if reconnected {
+				log.Info("Reconnected to the Docker events stream, resyncing containers")
+				defaultCache.invalidate(d.host)
+				select {
+				case out <- ContainerEvent{Action: ContainerEventResync, Time: clockNow()}:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+		stream:
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case msg := <-messages:
+					wait = watchReconnectInterval
+					defaultCache.invalidate(d.host)
+
+					event := ContainerEvent{
+						Action:      string(msg.Action),
+						ContainerID: shortID(msg.Actor.ID),
+						Name:        msg.Actor.Attributes["name"],
+						Image:       msg.Actor.Attributes["image"],
+						Time:        time.Unix(0, msg.TimeNano),
+					}
+
+					select {
+					case out <- event:
+					case <-ctx.Done():
+						return
+					}
+				case err := <-errs:
+					if ctx.Err() != nil || errors.Is(err, context.Canceled) {
+						return
+					}
+					log.Warn("Docker events stream dropped, reconnecting in %v: %v", wait, err)
+					reportConnectionState(socketPath, ConnectionDisconnected, err)
+					break stream
+				}
+			}
+
+			var ok bool
+			if wait, ok = d.awaitDaemon(ctx, log, wait); !ok {
+				return
+			}
+			reconnected = true
+		}
+	}()
+
+	return out
+}
+
+// awaitDaemon waits with backoff, starting at wait, until the daemon answers
+// again, so a stopped daemon is not hammered with subscriptions. It returns the
+// delay to use for the next drop, and false once ctx is cancelled.
+func (d *Client) awaitDaemon(ctx context.Context, log *logger.Entry, wait time.Duration) (time.Duration, bool) {
+	for {
+		select {
+		case <-ctx.Done():
+			return wait, false
+		case <-time.After(wait):
+		}
+
+		probeCtx, cancel := context.WithTimeout(ctx, DefaultTimeout)
+		_, err := d.cli.ServerVersion(probeCtx)
+		cancel()
+		if err == nil {
+			return min(2*wait, watchMaxReconnectInterval), true
+		}
+		if ctx.Err() != nil {
+			return wait, false
+		}
+
+		wait = min(2*wait, watchMaxReconnectInterval)
+		log.Warn("Docker daemon is still unreachable, retrying in %v: %v", wait, err)
+	}
+}
@@ -0,0 +1,110 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/docker/docker/api/types/container"
+)
+
+// ErrContainerNotFound is returned by FindContainer when no container matches
+var ErrContainerNotFound = errors.New("container not found")
+
+// FindContainer looks up a single container on the given socket, see Client.FindContainer
+func FindContainer(ctx context.Context, socketPath string, clientOpts ClientOptions, idOrName string) (*Container, error) {
+	dockerClient, err := NewClientWithOptions(socketPath, clientOpts)
+	if err != nil {
+		return nil, err
+	}
+	defer dockerClient.Close()
+
+	return dockerClient.FindContainer(ctx, idOrName)
+}
+
+// FindContainer returns the container with the given name, ID or unique ID prefix
+// (like the docker CLI) using a single inspect call. As the host container is not
+// looked up, TargetAddress uses the hostname rather than the bridge network
+// heuristic. ErrContainerNotFound is returned when nothing matches.
+func (d *Client) FindContainer(ctx context.Context, idOrName string) (*Container, error) {
+	idOrName = strings.TrimPrefix(strings.TrimSpace(idOrName), "/")
+	if idOrName == "" {
+		return nil, fmt.Errorf("%w: empty container ID or name", ErrContainerNotFound)
+	}
+
+	opts := ListOptions{}
+	info, err := withRetry(ctx, opts, "container inspect", func(ctx context.Context) (container.InspectResponse, error) {
+		return d.cli.ContainerInspect(ctx, idOrName)
+	})
+	if cerrdefs.IsNotFound(err) {
+		return nil, fmt.Errorf("%w: %s", ErrContainerNotFound, idOrName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect container %s: %w", idOrName, err)
+	}
+
+	state := listState{networks: make(map[string]networkDetails)}
+	dockerContainer := d.buildContainer(ctx, summaryFromInspect(info), &info, state, opts)
+	return &dockerContainer, nil
+}
+
+// summaryFromInspect maps an inspect response to the list summary buildContainer expects
+func summaryFromInspect(info container.InspectResponse) container.Summary {
+	summary := container.Summary{
+		ID:    info.ID,
+		Names: []string{info.Name},
+	}
+
+	if created, err := time.Parse(time.RFC3339Nano, info.Created); err == nil {
+		summary.Created = created.Unix()
+	}
+	if info.Config != nil {
+		summary.Image = info.Config.Image
+		summary.Labels = info.Config.Labels
+		summary.Command = strings.Join(append(append([]string{}, info.Config.Entrypoint...), info.Config.Cmd...), " ")
+	}
+	if info.State != nil {
+		summary.State = info.State.Status
+		summary.Status = string(info.State.Status)
+	}
+	if info.HostConfig != nil {
+		summary.HostConfig.NetworkMode = string(info.HostConfig.NetworkMode)
+	}
+
+	if info.NetworkSettings != nil {
+		summary.NetworkSettings = &container.NetworkSettingsSummary{Networks: info.NetworkSettings.Networks}
+
+		for port, bindings := range info.NetworkSettings.Ports {
+			privatePort := uint16(port.Int())
+			if len(bindings) == 0 {
+				summary.Ports = append(summary.Ports, container.Port{PrivatePort: privatePort, Type: port.Proto()})
+				continue
+			}
+			for _, binding := range bindings {
+				publicPort, _ := strconv.ParseUint(binding.HostPort, 10, 16)
+				summary.Ports = append(summary.Ports, container.Port{
+					IP:          binding.HostIP,
+					PrivatePort: privatePort,
+					PublicPort:  uint16(publicPort),
+					Type:        port.Proto(),
+				})
+			}
+		}
+		sort.Slice(summary.Ports, func(i, j int) bool {
+			if summary.Ports[i].PrivatePort != summary.Ports[j].PrivatePort {
+				return summary.Ports[i].PrivatePort < summary.Ports[j].PrivatePort
+			}
+			if summary.Ports[i].Type != summary.Ports[j].Type {
+				return summary.Ports[i].Type < summary.Ports[j].Type
+			}
+			return summary.Ports[i].IP < summary.Ports[j].IP
+		})
+	}
+
+	return summary
+}
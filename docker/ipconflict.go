@@ -0,0 +1,82 @@
+package docker
+
+import (
+	"net"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/fosrl/newt/logger"
+)
+
+// IPConflict is an IP address used by more than one container on the same network
+type IPConflict struct {
+	Network    string   `json:"network"`
+	IPAddress  string   `json:"ipAddress"`
+	Containers []string `json:"containers"` // container names
+}
+
+// DuplicateIPAddresses returns the IPv4 and IPv6 addresses shared by several
+// containers on the same network, sorted by network and address. This only
+// happens with broken custom IPAM setups, usually transiently, and makes
+// validation by IP match whichever container comes first.
+func DuplicateIPAddresses(containers []Container) []IPConflict {
+	owners := make(map[[2]string][]string)
+	for _, c := range containers {
+		for networkName, network := range c.Networks {
+			for _, address := range []string{network.IPAddress, network.GlobalIPv6Address} {
+				ip := net.ParseIP(address)
+				if ip == nil {
+					continue
+				}
+				// Normalize so compressed and expanded IPv6 forms are grouped
+				key := [2]string{networkName, ip.String()}
+				owners[key] = append(owners[key], c.Name)
+			}
+		}
+	}
+
+	var conflicts []IPConflict
+	for key, names := range owners {
+		if len(names) < 2 {
+			continue
+		}
+		sort.Strings(names)
+		conflicts = append(conflicts, IPConflict{Network: key[0], IPAddress: key[1], Containers: names})
+	}
+
+	sort.Slice(conflicts, func(i, j int) bool {
+		if conflicts[i].Network != conflicts[j].Network {
+			return conflicts[i].Network < conflicts[j].Network
+		}
+		return conflicts[i].IPAddress < conflicts[j].IPAddress
+	})
+	return conflicts
+}
+
+// ipConflictWarnings holds the socket, network and IP address of every conflict
+// already logged, so relisting does not repeat the warning
+var ipConflictWarnings sync.Map
+
+// markIPConflicts sets IPConflict on every container sharing an IP address with
+// another container on the same network and logs a warning once per conflict
+func markIPConflicts(socketPath string, containers []Container) {
+	conflicted := make(map[string]bool)
+	for _, conflict := range DuplicateIPAddresses(containers) {
+		key := socketPath + "/" + conflict.Network + "/" + conflict.IPAddress
+		if _, warned := ipConflictWarnings.LoadOrStore(key, struct{}{}); !warned {
+			logger.WithFields(logger.Fields{"socketPath": socketPath, "network": conflict.Network}).Warn(
+				"IP address %s is used by several containers (%s), targets may route to the wrong one",
+				conflict.IPAddress, strings.Join(conflict.Containers, ", "))
+		}
+		for _, name := range conflict.Containers {
+			conflicted[name] = true
+		}
+	}
+	if len(conflicted) == 0 {
+		return
+	}
+	for i := range containers {
+		containers[i].IPConflict = conflicted[containers[i].Name]
+	}
+}
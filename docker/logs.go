@@ -0,0 +1,76 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// MaxTailLines caps the number of log lines TailLogs returns
+const MaxTailLines = 1000
+
+// TailLogs returns the last lines of a container's logs on the given socket, see Client.TailLogs
+func TailLogs(ctx context.Context, socketPath string, clientOpts ClientOptions, containerID string, lines int) ([]string, error) {
+	dockerClient, err := NewClientWithOptions(socketPath, clientOpts)
+	if err != nil {
+		return nil, err
+	}
+	defer dockerClient.Close()
+
+	return dockerClient.TailLogs(ctx, containerID, lines)
+}
+
+// TailLogs returns up to lines of the most recent stdout and stderr output of a
+// container, oldest first, to help diagnose a misbehaving target. lines is capped
+// at MaxTailLines.
+func (d *Client) TailLogs(ctx context.Context, containerID string, lines int) ([]string, error) {
+	if lines <= 0 {
+		return nil, fmt.Errorf("invalid number of log lines: %d", lines)
+	}
+	lines = min(lines, MaxTailLines)
+
+	// Containers with a TTY stream raw output, others multiplex stdout and stderr
+	info, err := d.cli.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect container %s: %w", containerID, err)
+	}
+
+	reader, err := d.cli.ContainerLogs(ctx, containerID, container.LogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Tail:       strconv.Itoa(lines),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get logs of container %s: %w", containerID, err)
+	}
+	defer reader.Close()
+
+	var output bytes.Buffer
+	if info.Config != nil && info.Config.Tty {
+		_, err = io.Copy(&output, reader)
+	} else {
+		_, err = stdcopy.StdCopy(&output, &output, reader)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read logs of container %s: %w", containerID, err)
+	}
+
+	text := strings.TrimRight(output.String(), "\r\n")
+	if text == "" {
+		return nil, nil
+	}
+	logLines := strings.Split(text, "\n")
+	for i, line := range logLines {
+		logLines[i] = strings.TrimRight(line, "\r")
+	}
+	if len(logLines) > lines {
+		logLines = logLines[len(logLines)-lines:]
+	}
+	return logLines, nil
+}
@@ -0,0 +1,69 @@
+package docker
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/fosrl/newt/logger"
+)
+
+// MACConflict is a MAC address used by more than one container on the same network
+type MACConflict struct {
+	Network    string   `json:"network"`
+	MACAddress string   `json:"macAddress"`
+	Containers []string `json:"containers"` // container names
+}
+
+// DuplicateMACAddresses returns the MAC addresses shared by several containers on
+// the same network, sorted by network and address. Docker derives MAC addresses
+// from IPs, so equal MACs on different networks are expected and not reported.
+// Duplicates usually come from manually assigned MACs, e.g. on macvlan networks,
+// and break routing to the affected targets.
+func DuplicateMACAddresses(containers []Container) []MACConflict {
+	owners := make(map[[2]string][]string)
+	for _, c := range containers {
+		for networkName, network := range c.Networks {
+			if network.MacAddress == "" {
+				continue
+			}
+			key := [2]string{networkName, strings.ToLower(network.MacAddress)}
+			owners[key] = append(owners[key], c.Name)
+		}
+	}
+
+	var conflicts []MACConflict
+	for key, names := range owners {
+		if len(names) < 2 {
+			continue
+		}
+		sort.Strings(names)
+		conflicts = append(conflicts, MACConflict{Network: key[0], MACAddress: key[1], Containers: names})
+	}
+
+	sort.Slice(conflicts, func(i, j int) bool {
+		if conflicts[i].Network != conflicts[j].Network {
+			return conflicts[i].Network < conflicts[j].Network
+		}
+		return conflicts[i].MACAddress < conflicts[j].MACAddress
+	})
+	return conflicts
+}
+
+// macConflictWarnings holds the MAC address conflicts already logged, keyed by
+// socket, network, address and containers, so relisting does not repeat them
+var macConflictWarnings sync.Map
+
+// warnDuplicateMACAddresses logs a warning once for every MAC address conflict.
+// A conflict is logged again when the set of containers sharing the MAC changes.
+func warnDuplicateMACAddresses(socketPath string, containers []Container) {
+	for _, conflict := range DuplicateMACAddresses(containers) {
+		key := socketPath + "/" + conflict.Network + "/" + conflict.MACAddress + "/" + strings.Join(conflict.Containers, ",")
+		if _, warned := macConflictWarnings.LoadOrStore(key, struct{}{}); warned {
+			continue
+		}
+		logger.WithFields(logger.Fields{"socketPath": socketPath, "network": conflict.Network}).Warn(
+			"MAC address %s is used by several containers (%s), routing to them may fail",
+			conflict.MACAddress, strings.Join(conflict.Containers, ", "))
+	}
+}
@@ -0,0 +1,56 @@
+package docker
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Metrics receives observations about Docker discovery, e.g. to export them as
+// Prometheus counters and histograms. Implementations must be safe for
+// concurrent use as discovery runs from multiple goroutines.
+type Metrics interface {
+	// ListCompleted is called after every daemon listing with its duration,
+	// the number of containers discovered and the error, if any
+	ListCompleted(socketPath string, duration time.Duration, containers int, err error)
+	// InspectFailed is called when inspecting a single container fails
+	InspectFailed(socketPath string, err error)
+	// CacheHit is called when a listing is served from the cache
+	CacheHit(socketPath string)
+	// CacheMiss is called when a listing has to query the daemon
+	CacheMiss(socketPath string)
+	// ValidationCompleted is called after every target validation
+	ValidationCompleted(socketPath string, valid bool)
+}
+
+// noopMetrics discards every observation
+type noopMetrics struct{}
+
+func (noopMetrics) ListCompleted(string, time.Duration, int, error) {}
+func (noopMetrics) InspectFailed(string, error)                     {}
+func (noopMetrics) CacheHit(string)                                 {}
+func (noopMetrics) CacheMiss(string)                                {}
+func (noopMetrics) ValidationCompleted(string, bool)                {}
+
+// metricsHolder wraps the interface so atomic.Value always stores one concrete type
+type metricsHolder struct {
+	metrics Metrics
+}
+
+var currentMetrics atomic.Value
+
+// SetMetrics installs the Metrics implementation used by the package. Passing
+// nil restores the default, which discards all observations.
+func SetMetrics(m Metrics) {
+	if m == nil {
+		m = noopMetrics{}
+	}
+	currentMetrics.Store(metricsHolder{m})
+}
+
+// metrics returns the installed Metrics implementation
+func metrics() Metrics {
+	if holder, ok := currentMetrics.Load().(metricsHolder); ok {
+		return holder.metrics
+	}
+	return noopMetrics{}
+}
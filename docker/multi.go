@@ -0,0 +1,140 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/fosrl/newt/logger"
+)
+
+// SplitSocketPaths splits a comma separated list of socket paths or Docker host URIs
+func SplitSocketPaths(socketPaths string) []string {
+	var paths []string
+	for _, path := range strings.Split(socketPaths, ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// ListContainersFromSockets lists containers from several Docker daemons, e.g. a
+// rootless and a rootful daemon on the same host, see ListContainersFromClients
+func ListContainersFromSockets(ctx context.Context, socketPaths []string, clientOpts ClientOptions, enforceNetworkValidation bool, opts ListOptions) ([]Container, error) {
+	clients, err := newClients(socketPaths, clientOpts)
+	if err != nil {
+		return nil, err
+	}
+	defer closeClients(clients)
+
+	return ListContainersFromClients(ctx, clients, enforceNetworkValidation, opts)
+}
+
+// ListContainersFromClients lists containers from every client concurrently and
+// merges them in client order. Container.SourceSocket records the origin. A
+// daemon that is down is logged and skipped; an error is only returned when
+// every daemon fails or, along with the containers, when some inspects failed
+// (see ErrPartialResults).
+func ListContainersFromClients(ctx context.Context, clients []*Client, enforceNetworkValidation bool, opts ListOptions) ([]Container, error) {
+	results := make([][]Container, len(clients))
+	errs := make([]error, len(clients))
+
+	var wg sync.WaitGroup
+	for i, dockerClient := range clients {
+		wg.Add(1)
+		go func(i int, dockerClient *Client) {
+			defer wg.Done()
+			results[i], errs[i] = dockerClient.ListContainers(ctx, enforceNetworkValidation, opts)
+		}(i, dockerClient)
+	}
+	wg.Wait()
+
+	var merged []Container
+	var failures, partial []error
+	for i, dockerClient := range clients {
+		if fatalListError(errs[i]) {
+			logger.WithFields(logger.Fields{"socketPath": dockerClient.SocketPath()}).Warn("Failed to list containers: %v", errs[i])
+			failures = append(failures, fmt.Errorf("%s: %w", dockerClient.SocketPath(), errs[i]))
+			continue
+		}
+		if errs[i] != nil {
+			partial = append(partial, fmt.Errorf("%s: %w", dockerClient.SocketPath(), errs[i]))
+		}
+		merged = append(merged, results[i]...)
+	}
+
+	if len(clients) > 0 && len(failures) == len(clients) {
+		return nil, errors.Join(failures...)
+	}
+	if len(clients) > 1 {
+		warnDuplicateNames(merged)
+	}
+	return merged, errors.Join(partial...)
+}
+
+// IsWithinHostNetworkOnSockets validates the target against every Docker daemon
+// and succeeds if any of them can reach it
+func IsWithinHostNetworkOnSockets(ctx context.Context, socketPaths []string, clientOpts ClientOptions, targetAddress string, startPort int, endPort int, opts ValidationOptions) (bool, error) {
+	clients, err := newClients(socketPaths, clientOpts)
+	if err != nil {
+		return false, err
+	}
+	defer closeClients(clients)
+
+	return IsWithinHostNetworkOnClients(ctx, clients, targetAddress, startPort, endPort, opts)
+}
+
+// IsWithinHostNetworkOnClients validates the target against every client and
+// succeeds if any of them can reach it. The errors of all clients are joined otherwise.
+// A hostname target valid on several daemons is rejected with ErrAmbiguousMatch.
+func IsWithinHostNetworkOnClients(ctx context.Context, clients []*Client, targetAddress string, startPort int, endPort int, opts ValidationOptions) (bool, error) {
+	var failures []error
+	var candidates []Container
+	for _, dockerClient := range clients {
+		c, _, err := dockerClient.MatchContainerWithOptions(ctx, targetAddress, startPort, endPort, opts)
+		if err != nil {
+			failures = append(failures, fmt.Errorf("%s: %w", dockerClient.SocketPath(), err))
+			continue
+		}
+		// Host gateway targets are valid on every daemon publishing the ports
+		if c == nil || net.ParseIP(strings.TrimSpace(targetAddress)) != nil || opts.GlobNames {
+			return true, nil
+		}
+		candidates = append(candidates, *c)
+	}
+	if len(candidates) > 0 {
+		if err := ambiguousMatch(targetAddress, candidates); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+	if len(failures) == 0 {
+		return false, fmt.Errorf("no Docker sockets configured")
+	}
+	return false, errors.Join(failures...)
+}
+
+// newClients creates a client per socket path
+func newClients(socketPaths []string, clientOpts ClientOptions) ([]*Client, error) {
+	clients := make([]*Client, 0, len(socketPaths))
+	for _, socketPath := range socketPaths {
+		dockerClient, err := NewClientWithOptions(socketPath, clientOpts)
+		if err != nil {
+			closeClients(clients)
+			return nil, err
+		}
+		clients = append(clients, dockerClient)
+	}
+	return clients, nil
+}
+
+// closeClients closes every client
+func closeClients(clients []*Client) {
+	for _, dockerClient := range clients {
+		dockerClient.Close()
+	}
+}
@@ -0,0 +1,97 @@
+package docker
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fosrl/newt/logger"
+)
+
+// ErrAmbiguousMatch is returned by validation when a hostname target matches
+// containers of different services, e.g. the same name on two daemons or the
+// same alias in two compose projects. Use the container ID to pick one.
+var ErrAmbiguousMatch = errors.New("target matches several containers")
+
+// NameConflict is a container name used by more than one discovered container,
+// which happens when listing several daemons
+type NameConflict struct {
+	Name       string   `json:"name"`
+	Containers []string `json:"containers"` // container IDs with their source socket, e.g. 0123456789ab@unix:///var/run/docker.sock
+}
+
+// DuplicateNames returns the container names shared by several containers, sorted by name
+func DuplicateNames(containers []Container) []NameConflict {
+	owners := make(map[string][]string)
+	for _, c := range containers {
+		owners[c.Name] = append(owners[c.Name], containerRef(c))
+	}
+
+	var conflicts []NameConflict
+	for name, refs := range owners {
+		if len(refs) < 2 {
+			continue
+		}
+		sort.Strings(refs)
+		conflicts = append(conflicts, NameConflict{Name: name, Containers: refs})
+	}
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].Name < conflicts[j].Name
+	})
+	return conflicts
+}
+
+// warnDuplicateNames logs a warning for every container name used more than once
+func warnDuplicateNames(containers []Container) {
+	for _, conflict := range DuplicateNames(containers) {
+		logger.Warn("Container name %s is used by several containers (%s), validate targets by container ID instead",
+			conflict.Name, strings.Join(conflict.Containers, ", "))
+	}
+}
+
+// containerRef identifies a container across daemons for messages
+func containerRef(c Container) string {
+	if c.SourceSocket == "" {
+		return c.ID
+	}
+	return c.ID + "@" + c.SourceSocket
+}
+
+// containerIDMatches reports whether the target is a prefix of the container's
+// full ID at least as long as the 12 character short ID, so "docker ps" IDs and
+// full IDs both select the container
+func containerIDMatches(c Container, target string) bool {
+	id := c.fullID
+	if id == "" {
+		id = c.ID
+	}
+	return len(target) >= 12 && strings.HasPrefix(id, target)
+}
+
+// ambiguousMatch returns an ErrAmbiguousMatch error when the candidates belong to
+// different services. Replicas of one compose service share their alias on
+// purpose, Docker DNS balances between them, so they are not ambiguous.
+func ambiguousMatch(target string, candidates []Container) error {
+	services := make(map[string]bool)
+	for _, c := range candidates {
+		services[serviceKey(c)] = true
+	}
+	if len(services) < 2 {
+		return nil
+	}
+
+	refs := make([]string, len(candidates))
+	for i, c := range candidates {
+		refs[i] = fmt.Sprintf("%s (%s)", c.Name, containerRef(c))
+	}
+	return fmt.Errorf("%w: %s matches %s, use a container ID to choose one", ErrAmbiguousMatch, target, strings.Join(refs, ", "))
+}
+
+// serviceKey groups the replicas of a compose service, other containers stand alone
+func serviceKey(c Container) string {
+	if c.ComposeService != "" {
+		return c.SourceSocket + "/" + c.ComposeProject + "/" + c.ComposeService
+	}
+	return c.SourceSocket + "/" + c.ID
+}
@@ -0,0 +1,121 @@
+package docker
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestContainerIDMatches(t *testing.T) {
+	const fullID = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	c := Container{ID: fullID[:12], fullID: fullID}
+
+	tests := []struct {
+		name   string
+		target string
+		want   bool
+	}{
+		{"short ID", "0123456789ab", true},
+		{"full ID", fullID, true},
+		{"longer prefix", "0123456789abcdef", true},
+		{"prefix shorter than the short ID", "0123456789a", false},
+		{"short ID with a suffix", "0123456789abXYZ", false},
+		{"other ID", "fedcba9876543210", false},
+		{"empty", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := containerIDMatches(c, tt.target); got != tt.want {
+				t.Errorf("containerIDMatches(%q) = %t, want %t", tt.target, got, tt.want)
+			}
+		})
+	}
+
+	// Containers built without the full ID, e.g. by callers, still match their short ID
+	if !containerIDMatches(Container{ID: fullID[:12]}, fullID[:12]) {
+		t.Errorf("containerIDMatches without fullID did not match the short ID")
+	}
+}
+
+func TestAmbiguousMatch(t *testing.T) {
+	replica := func(id string) Container {
+		return Container{ID: id, Name: "shop-web-" + id, ComposeProject: "shop", ComposeService: "web"}
+	}
+
+	tests := []struct {
+		name       string
+		candidates []Container
+		wantErr    bool
+	}{
+		{"single container", []Container{{ID: "a", Name: "web"}}, false},
+		{"replicas of one service", []Container{replica("1"), replica("2")}, false},
+		{"same service in two projects", []Container{replica("1"), {ID: "2", Name: "blog-web-1", ComposeProject: "blog", ComposeService: "web"}}, true},
+		{"same name on two daemons", []Container{{ID: "a", Name: "web", SourceSocket: "unix:///a.sock"}, {ID: "b", Name: "web", SourceSocket: "unix:///b.sock"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ambiguousMatch("web", tt.candidates)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ambiguousMatch error = %v, want error %t", err, tt.wantErr)
+			}
+			if err == nil {
+				return
+			}
+			if !errors.Is(err, ErrAmbiguousMatch) {
+				t.Errorf("error %v does not wrap ErrAmbiguousMatch", err)
+			}
+			for _, c := range tt.candidates {
+				if !strings.Contains(err.Error(), c.Name) {
+					t.Errorf("error %q does not list candidate %s", err, c.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestDuplicateNames(t *testing.T) {
+	containers := []Container{
+		{ID: "a", Name: "web", SourceSocket: "unix:///a.sock"},
+		{ID: "b", Name: "web", SourceSocket: "unix:///b.sock"},
+		{ID: "c", Name: "db", SourceSocket: "unix:///a.sock"},
+	}
+	conflicts := DuplicateNames(containers)
+	if len(conflicts) != 1 || conflicts[0].Name != "web" {
+		t.Fatalf("DuplicateNames = %+v, want one conflict for web", conflicts)
+	}
+	want := []string{"a@unix:///a.sock", "b@unix:///b.sock"}
+	if strings.Join(conflicts[0].Containers, ",") != strings.Join(want, ",") {
+		t.Errorf("conflict containers = %v, want %v", conflicts[0].Containers, want)
+	}
+}
+
+func TestNamesEqual(t *testing.T) {
+	lenient := ValidationOptions{LenientNameMatching: true}
+	glob := ValidationOptions{GlobNames: true}
+
+	tests := []struct {
+		name   string
+		target string
+		opts   ValidationOptions
+		want   bool
+	}{
+		{"myapp", "MyApp", ValidationOptions{}, false},
+		{"myapp", "MyApp", lenient, true},
+		{"MyApp", "myapp", lenient, true},
+		{"api", " api ", ValidationOptions{}, false},
+		{"api", " api ", lenient, true},
+		{"api", " API\t", lenient, true},
+		{"api", "apis", lenient, false},
+		{"web-1", "web-*", glob, true},
+		{"web-1", "Web-*", glob, false},
+		{"web-1", " Web-* ", ValidationOptions{LenientNameMatching: true, GlobNames: true}, true},
+		{"web[", "web[", glob, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name+"/"+tt.target, func(t *testing.T) {
+			if got := namesEqual(tt.name, tt.target, tt.opts); got != tt.want {
+				t.Errorf("namesEqual(%q, %q, %+v) = %t, want %t", tt.name, tt.target, tt.opts, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package docker
+
+import (
+	"context"
+	"net"
+	"sort"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/fosrl/newt/logger"
+)
+
+// networkDetails is what a listing needs to know about a Docker network
+type networkDetails struct {
+	subnets  []string // IPAM subnets in CIDR notation
+	internal bool     // created with --internal, so it has no route outside Docker
+}
+
+// networkDetails returns the details of the Docker network. Network inspects are
+// memoized in details by network ID, so containers sharing a network only cost
+// one lookup per listing.
+func (d *Client) networkDetails(ctx context.Context, opts ListOptions, details map[string]networkDetails, networkID string) networkDetails {
+	if networkID == "" || opts.SkipInspect {
+		return networkDetails{}
+	}
+
+	cached, ok := details[networkID]
+	if !ok {
+		info, err := withRetry(ctx, opts, "network inspect", func(ctx context.Context) (network.Inspect, error) {
+			return d.cli.NetworkInspect(ctx, networkID, network.InspectOptions{})
+		})
+		if err != nil {
+			logger.Debug("Failed to inspect network %s: %v", networkID, err)
+		}
+		for _, config := range info.IPAM.Config {
+			if config.Subnet != "" {
+				cached.subnets = append(cached.subnets, config.Subnet)
+			}
+		}
+		cached.internal = info.Internal
+		// Failures are memoized too so a missing network is not retried for every container
+		details[networkID] = cached
+	}
+	return cached
+}
+
+// subnet returns the subnet containing ip, or the network's first subnet when ip
+// is empty or not in any of them
+func (n networkDetails) subnet(ip string) string {
+	candidates := n.subnets
+	if len(candidates) == 0 {
+		return ""
+	}
+
+	if parsedIP := net.ParseIP(ip); parsedIP != nil {
+		for _, subnet := range candidates {
+			if _, ipNet, err := net.ParseCIDR(subnet); err == nil && ipNet.Contains(parsedIP) {
+				return subnet
+			}
+		}
+	}
+	return candidates[0]
+}
+
+// SharedNetworks returns the sorted names of the routable networks present in
+// both network maps, e.g. Newt's and a target container's Networks
+func SharedNetworks(a map[string]Network, b map[string]Network) []string {
+	var shared []string
+	for name := range a {
+		if _, ok := b[name]; ok && routableNetwork(name) {
+			shared = append(shared, name)
+		}
+	}
+	sort.Strings(shared)
+	return shared
+}
+
+// ReachablePorts returns the ports of the target Newt can reach from the host
+// networks. It is a reachability check of the whole container rather than a
+// per port filter: Docker does not scope ports to networks, a port the target
+// listens on is reachable through each of its endpoints. The result is all of
+// the target's ports, each private port listed once, when at least one shared
+// network has an address on both sides, and none otherwise. Networks the
+// target is attached to but Newt is not never make ports reachable.
+func ReachablePorts(hostNetworks map[string]Network, target Container) []Port {
+	if len(addressedSharedNetworks(hostNetworks, target.Networks)) == 0 {
+		return nil
+	}
+
+	type portKey struct {
+		private  int
+		protocol string
+	}
+	seen := make(map[portKey]bool)
+	var reachable []Port
+	for _, port := range target.Ports {
+		key := portKey{port.PrivatePort, port.Type}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		reachable = append(reachable, port)
+	}
+	return reachable
+}
+
+// addressedSharedNetworks returns the shared networks, see SharedNetworks, on
+// which both endpoints have an IPv4 or IPv6 address to route through
+func addressedSharedNetworks(a map[string]Network, b map[string]Network) []string {
+	var addressed []string
+	for _, name := range SharedNetworks(a, b) {
+		if endpointHasAddress(a[name]) && endpointHasAddress(b[name]) {
+			addressed = append(addressed, name)
+		}
+	}
+	return addressed
+}
+
+// endpointHasAddress reports whether the network endpoint has an IPv4 or IPv6 address
+func endpointHasAddress(n Network) bool {
+	return n.IPAddress != "" || n.GlobalIPv6Address != ""
+}
+
+// ReachablePorts returns the ports of the target reachable from the networks of
+// the container Newt runs in, see ReachablePorts. It returns ErrHostContainerNotFound
+// when Newt is not running in a container on this daemon.
+func (d *Client) ReachablePorts(ctx context.Context, target Container) ([]Port, error) {
+	hostContainer, err := getHostContainer(ctx, d.cli)
+	if err != nil {
+		return nil, err
+	}
+	return ReachablePorts(inspectNetworks(hostContainer), target), nil
+}
+
+// inspectNetworks returns the network names of an inspected container as a
+// Networks map. Only the fields needed to intersect networks are filled in.
+func inspectNetworks(info *container.InspectResponse) map[string]Network {
+	networks := make(map[string]Network)
+	if info == nil || info.NetworkSettings == nil {
+		return networks
+	}
+	for name, endpoint := range info.NetworkSettings.Networks {
+		if endpoint == nil {
+			continue
+		}
+		networks[name] = Network{NetworkID: endpoint.NetworkID, IPAddress: endpoint.IPAddress, GlobalIPv6Address: endpoint.GlobalIPv6Address}
+	}
+	return networks
+}
@@ -0,0 +1,38 @@
+package docker
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestReachablePorts(t *testing.T) {
+	ports := []Port{
+		{PrivatePort: 80, Type: "tcp"},
+		{PrivatePort: 80, PublicPort: 8080, Type: "tcp", IP: "0.0.0.0"},
+		{PrivatePort: 53, Type: "udp"},
+	}
+	want := []Port{{PrivatePort: 80, Type: "tcp"}, {PrivatePort: 53, Type: "udp"}}
+
+	newt := map[string]Network{"proxy": {IPAddress: "172.20.0.2"}}
+
+	tests := []struct {
+		name     string
+		networks map[string]Network
+		want     []Port
+	}{
+		{"shared network", map[string]Network{"proxy": {IPAddress: "172.20.0.5"}}, want},
+		{"partially shared networks", map[string]Network{"proxy": {IPAddress: "172.20.0.5"}, "backend": {IPAddress: "172.21.0.5"}}, want},
+		{"ipv6 only endpoint", map[string]Network{"proxy": {GlobalIPv6Address: "fd00::5"}}, want},
+		{"only networks Newt is not on", map[string]Network{"backend": {IPAddress: "172.21.0.5"}}, nil},
+		{"shared network without an address", map[string]Network{"proxy": {}, "backend": {IPAddress: "172.21.0.5"}}, nil},
+		{"host network", map[string]Network{"host": {}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ReachablePorts(newt, Container{Name: "web", Ports: ports, Networks: tt.networks})
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ReachablePorts = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
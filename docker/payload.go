@@ -0,0 +1,76 @@
+package docker
+
+import (
+	"fmt"
+)
+
+// PangolinTarget is the target Pangolin creates for a container. It is the single
+// place the Container to target mapping is defined, so its JSON shape is part of
+// the contract with Pangolin and must only change deliberately.
+type PangolinTarget struct {
+	Name     string       `json:"name"`
+	Address  string       `json:"address"`  // resolved target address, see ResolveTargetAddress
+	Port     int          `json:"port"`     // port the target points at
+	Protocol string       `json:"protocol"` // protocol of Port, tcp or udp
+	Ports    []TargetPort `json:"ports"`    // every port the container serves on
+}
+
+// TargetPort is a container port a target can point at
+type TargetPort struct {
+	Port     int    `json:"port"`
+	Protocol string `json:"protocol"`
+}
+
+// ToTarget maps a container to the target Pangolin expects. The address honors the
+// target labels and the address selected during discovery. The port is the
+// TargetPortLabel value, or the lowest container port. Ports lists the private
+// ports once per protocol, as Newt reaches containers directly rather than
+// through published host ports. An error is returned when the container has no
+// resolvable address or no port.
+func ToTarget(c Container) (PangolinTarget, error) {
+	address, err := ResolveTargetAddress(c)
+	if err != nil {
+		return PangolinTarget{}, err
+	}
+
+	ports := targetPorts(c.Ports)
+	target := PangolinTarget{Name: c.Name, Address: address, Ports: ports}
+
+	switch {
+	case c.TargetPort != 0:
+		target.Port, target.Protocol = c.TargetPort, "tcp"
+		for _, port := range ports {
+			if port.Port == c.TargetPort {
+				target.Protocol = port.Protocol
+				break
+			}
+		}
+	case len(ports) > 0:
+		target.Port, target.Protocol = ports[0].Port, ports[0].Protocol
+	default:
+		return PangolinTarget{}, fmt.Errorf("container %s has no port to target", c.Name)
+	}
+	return target, nil
+}
+
+// targetPorts returns the distinct private ports in the order of ports, which
+// discovery sorts by port and protocol
+func targetPorts(ports []Port) []TargetPort {
+	seen := make(map[TargetPort]bool)
+	targets := []TargetPort{}
+	for _, port := range ports {
+		if port.PrivatePort == 0 {
+			continue
+		}
+		protocol := port.Type
+		if protocol == "" {
+			protocol = "tcp"
+		}
+		target := TargetPort{Port: port.PrivatePort, Protocol: protocol}
+		if !seen[target] {
+			seen[target] = true
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
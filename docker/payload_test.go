@@ -0,0 +1,76 @@
+package docker
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// TestToTargetShape pins the JSON Pangolin receives, a change here breaks the contract
+func TestToTargetShape(t *testing.T) {
+	tests := []struct {
+		name string
+		c    Container
+		want string
+	}{
+		{
+			name: "lowest port",
+			c: Container{
+				Name:          "web",
+				TargetAddress: "172.18.0.2",
+				Ports: []Port{
+					{PrivatePort: 53, Type: "udp"},
+					{PrivatePort: 80, Type: "tcp", PublicPort: 8080, IP: "0.0.0.0"},
+					{PrivatePort: 80, Type: "tcp", PublicPort: 8080, IP: "::"},
+					{PrivatePort: 443},
+				},
+			},
+			want: `{"name":"web","address":"172.18.0.2","port":53,"protocol":"udp","ports":[{"port":53,"protocol":"udp"},{"port":80,"protocol":"tcp"},{"port":443,"protocol":"tcp"}]}`,
+		},
+		{
+			name: "port label",
+			c: Container{
+				Name:          "api",
+				TargetAddress: "api",
+				TargetPort:    443,
+				Ports:         []Port{{PrivatePort: 80, Type: "tcp"}, {PrivatePort: 443, Type: "udp"}},
+			},
+			want: `{"name":"api","address":"api","port":443,"protocol":"udp","ports":[{"port":80,"protocol":"tcp"},{"port":443,"protocol":"udp"}]}`,
+		},
+		{
+			name: "port label without exposed ports",
+			c:    Container{Name: "db", TargetAddress: "db", TargetPort: 5432},
+			want: `{"name":"db","address":"db","port":5432,"protocol":"tcp","ports":[]}`,
+		},
+		{
+			name: "address label",
+			c: Container{
+				Name:          "proxy",
+				TargetAddress: "172.18.0.3",
+				Labels:        map[string]string{TargetAddressLabel: "10.0.0.5"},
+				Ports:         []Port{{PrivatePort: 8080, Type: "tcp"}},
+			},
+			want: `{"name":"proxy","address":"10.0.0.5","port":8080,"protocol":"tcp","ports":[{"port":8080,"protocol":"tcp"}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, err := ToTarget(tt.c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := json.Marshal(target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("ToTarget JSON =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToTargetWithoutPorts(t *testing.T) {
+	if _, err := ToTarget(Container{Name: "web", TargetAddress: "web"}); err == nil {
+		t.Errorf("a container without ports was mapped to a target")
+	}
+}
@@ -0,0 +1,102 @@
+package docker
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ExcludePortsLabel lists ports of a container that must never be advertised or
+// validated as targets, e.g. "9000,9100-9110" for admin interfaces
+const ExcludePortsLabel = "newt.exclude-ports"
+
+// PortRange is an inclusive range of port numbers. A single port has Start == End.
+type PortRange struct {
+	Start int
+	End   int
+}
+
+// Contains reports whether port is within the range
+func (r PortRange) Contains(port int) bool {
+	return port >= r.Start && port <= r.End
+}
+
+// String renders the range as "8080" or "9000-9100"
+func (r PortRange) String() string {
+	if r.Start == r.End {
+		return strconv.Itoa(r.Start)
+	}
+	return fmt.Sprintf("%d-%d", r.Start, r.End)
+}
+
+// ParsePortRanges parses a comma separated list of ports and port ranges such as
+// "8080,9000-9100". Empty entries are ignored.
+func ParsePortRanges(value string) ([]PortRange, error) {
+	var ranges []PortRange
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		startValue, endValue, isRange := strings.Cut(part, "-")
+		start, err := parsePortNumber(startValue)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port range %q: %w", part, err)
+		}
+		end := start
+		if isRange {
+			if end, err = parsePortNumber(endValue); err != nil {
+				return nil, fmt.Errorf("invalid port range %q: %w", part, err)
+			}
+		}
+		if start > end {
+			return nil, fmt.Errorf("invalid port range %q: start is after end", part)
+		}
+		ranges = append(ranges, PortRange{Start: start, End: end})
+	}
+	return ranges, nil
+}
+
+// parsePortNumber parses a port between 1 and 65535
+func parsePortNumber(value string) (int, error) {
+	port, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || port < 1 || port > 65535 {
+		return 0, fmt.Errorf("%q is not a port between 1 and 65535", value)
+	}
+	return port, nil
+}
+
+// portExcluded reports whether the private or public port is in one of the ranges
+func portExcluded(port Port, ranges []PortRange) bool {
+	for _, r := range ranges {
+		if r.Contains(port.PrivatePort) || (port.PublicPort != 0 && r.Contains(port.PublicPort)) {
+			return true
+		}
+	}
+	return false
+}
+
+// removeExcludedPorts drops the ports excluded by the options or the container's
+// ExcludePortsLabel. An invalid label is logged by the caller and ignored.
+func removeExcludedPorts(ports []Port, excluded []PortRange, labels map[string]string) ([]Port, error) {
+	var labelErr error
+	if value, ok := labels[ExcludePortsLabel]; ok {
+		labelRanges, err := ParsePortRanges(value)
+		if err != nil {
+			labelErr = fmt.Errorf("invalid %s label: %w", ExcludePortsLabel, err)
+		}
+		excluded = append(excluded[:len(excluded):len(excluded)], labelRanges...)
+	}
+	if len(excluded) == 0 {
+		return ports, labelErr
+	}
+
+	kept := ports[:0:0]
+	for _, port := range ports {
+		if !portExcluded(port, excluded) {
+			kept = append(kept, port)
+		}
+	}
+	return kept, labelErr
+}
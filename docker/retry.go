@@ -0,0 +1,108 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+	"time"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/docker/docker/client"
+	"github.com/fosrl/newt/logger"
+)
+
+const (
+	// DefaultRetryAttempts is the default number of tries for a Docker API call
+	DefaultRetryAttempts = 3
+	// DefaultRetryBaseDelay is the default delay before the first retry
+	DefaultRetryBaseDelay = 100 * time.Millisecond
+)
+
+// RetryPolicy controls how transient Docker API failures are retried
+type RetryPolicy struct {
+	// Attempts is the total number of tries per call. Defaults to
+	// DefaultRetryAttempts, set it to 1 to disable retries.
+	Attempts int
+
+	// BaseDelay is the wait before the first retry and doubles after every
+	// further attempt. Defaults to DefaultRetryBaseDelay.
+	BaseDelay time.Duration
+}
+
+// attempts returns the configured number of tries or the default
+func (p RetryPolicy) attempts() int {
+	if p.Attempts <= 0 {
+		return DefaultRetryAttempts
+	}
+	return p.Attempts
+}
+
+// baseDelay returns the configured base delay or the default
+func (p RetryPolicy) baseDelay() time.Duration {
+	if p.BaseDelay <= 0 {
+		return DefaultRetryBaseDelay
+	}
+	return p.BaseDelay
+}
+
+// withRetry runs fn with a per-attempt timeout, retrying transient failures with
+// exponential backoff until the policy is exhausted or ctx is done. No retry is
+// started when ctx would expire during the backoff.
+func withRetry[T any](ctx context.Context, opts ListOptions, operation string, fn func(context.Context) (T, error)) (T, error) {
+	policy := opts.Retry
+	delay := policy.baseDelay()
+
+	var result T
+	var err error
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, opts.timeout())
+		result, err = fn(attemptCtx)
+		cancel()
+
+		if err == nil || attempt >= policy.attempts() || ctx.Err() != nil || !isTransientError(err) {
+			return result, err
+		}
+
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= delay {
+			return result, err
+		}
+
+		logger.Debug("Transient Docker error during %s (attempt %d/%d), retrying in %v: %v", operation, attempt, policy.attempts(), delay, err)
+
+		select {
+		case <-ctx.Done():
+			return result, err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// isTransientError reports whether a Docker API error is worth retrying. Connection
+// problems and daemon side failures are retried, cancellation, timeouts and request
+// errors such as not found or permission denied are not. A daemon that did not
+// answer within the timeout is unlikely to answer the next attempt either.
+func isTransientError(err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return false
+	case cerrdefs.IsNotFound(err), cerrdefs.IsInvalidArgument(err), cerrdefs.IsConflict(err),
+		cerrdefs.IsUnauthorized(err), cerrdefs.IsPermissionDenied(err), cerrdefs.IsNotImplemented(err):
+		return false
+	case client.IsErrConnectionFailed(err):
+		return true
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.EPIPE):
+		return true
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return true
+	case cerrdefs.IsUnavailable(err), cerrdefs.IsInternal(err):
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
@@ -0,0 +1,47 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestWithRetry(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		deadline time.Duration
+		want     int
+	}{
+		{"connection reset is retried", syscall.ECONNRESET, 0, 3},
+		{"timeout is not retried", fmt.Errorf("list: %w", context.DeadlineExceeded), 0, 1},
+		{"plain errors are not retried", errors.New("boom"), 0, 1},
+		{"no retry past the caller's deadline", syscall.ECONNRESET, 5 * time.Millisecond, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.deadline > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.deadline)
+				defer cancel()
+			}
+
+			calls := 0
+			opts := ListOptions{Retry: RetryPolicy{Attempts: 3, BaseDelay: 10 * time.Millisecond}}
+			_, err := withRetry(ctx, opts, "test", func(context.Context) (struct{}, error) {
+				calls++
+				return struct{}{}, tt.err
+			})
+			if !errors.Is(err, tt.err) {
+				t.Errorf("error = %v, want %v", err, tt.err)
+			}
+			if calls != tt.want {
+				t.Errorf("called %d times, want %d", calls, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package docker
+
+import (
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// containerIDPattern matches a full 64 character container ID
+var containerIDPattern = regexp.MustCompile(`[0-9a-f]{64}`)
+
+// containerBindMounts are the files Docker bind mounts into every container
+// from /var/lib/docker/containers/<id>/
+var containerBindMounts = map[string]bool{
+	"/etc/hostname":    true,
+	"/etc/hosts":       true,
+	"/etc/resolv.conf": true,
+}
+
+// selfContainerID returns the ID of the container Newt runs in as found in the
+// cgroup or mount paths of the process, or an empty string when it is not found.
+// This works when the hostname was customized and no longer equals the ID. The
+// files are read once, a process does not move between containers.
+var selfContainerID = sync.OnceValue(func() string {
+	cgroup, _ := os.ReadFile("/proc/self/cgroup")
+	mountinfo, _ := os.ReadFile("/proc/self/mountinfo")
+	return parseSelfContainerID(string(cgroup), string(mountinfo))
+})
+
+// parseSelfContainerID implements selfContainerID on the contents of
+// /proc/self/cgroup and /proc/self/mountinfo
+func parseSelfContainerID(cgroup string, mountinfo string) string {
+	// cgroup v1 paths end in the ID, e.g. /docker/<id> or /system.slice/docker-<id>.scope
+	for _, line := range strings.Split(cgroup, "\n") {
+		if id := containerIDPattern.FindString(line); id != "" {
+			return id
+		}
+	}
+
+	// cgroup v2 only shows "0::/", but Docker bind mounts /etc/hostname and
+	// friends from /var/lib/docker/containers/<id>/. Only these mount points are
+	// accepted: outside a container mountinfo lists the shm mounts of every
+	// other container under the same directory.
+	for _, line := range strings.Split(mountinfo, "\n") {
+		// Fields are: mount ID, parent ID, major:minor, root, mount point, ...
+		fields := strings.Fields(line)
+		if len(fields) < 5 || !containerBindMounts[fields[4]] {
+			continue
+		}
+		if root := fields[3]; strings.Contains(root, "/containers/") {
+			if id := containerIDPattern.FindString(root); id != "" {
+				return id
+			}
+		}
+	}
+
+	return ""
+}
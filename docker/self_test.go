@@ -0,0 +1,64 @@
+package docker
+
+import "testing"
+
+func TestParseSelfContainerID(t *testing.T) {
+	const id = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	const other = "fedcba9876543210fedcba9876543210fedcba9876543210fedcba9876543210"
+
+	tests := []struct {
+		name      string
+		cgroup    string
+		mountinfo string
+		want      string
+	}{
+		{
+			name:   "cgroup v1",
+			cgroup: "12:memory:/docker/" + id + "\n11:cpu,cpuacct:/docker/" + id + "\n",
+			want:   id,
+		},
+		{
+			name:   "cgroup v1 systemd driver",
+			cgroup: "1:name=systemd:/system.slice/docker-" + id + ".scope\n",
+			want:   id,
+		},
+		{
+			name:   "cgroup v2",
+			cgroup: "0::/\n",
+			mountinfo: "" +
+				"500 480 0:52 / / rw,relatime - overlay overlay rw,lowerdir=/var/lib/docker/overlay2/l/ABC\n" +
+				"520 500 0:55 / /dev/shm rw,nosuid - tmpfs shm rw,size=65536k\n" +
+				"530 500 8:1 /var/lib/docker/containers/" + id + "/resolv.conf /etc/resolv.conf rw,relatime - ext4 /dev/sda1 rw\n" +
+				"531 500 8:1 /var/lib/docker/containers/" + id + "/hostname /etc/hostname rw,relatime - ext4 /dev/sda1 rw\n" +
+				"532 500 8:1 /var/lib/docker/containers/" + id + "/hosts /etc/hosts rw,relatime - ext4 /dev/sda1 rw\n",
+			want: id,
+		},
+		{
+			name:   "host with other containers",
+			cgroup: "0::/user.slice/user-1000.slice/session-2.scope\n",
+			mountinfo: "" +
+				"22 1 8:1 / / rw,relatime shared:1 - ext4 /dev/sda1 rw\n" +
+				"610 22 0:60 / /var/lib/docker/containers/" + other + "/mounts/shm rw,nosuid shared:300 - tmpfs shm rw,size=65536k\n" +
+				"620 22 0:61 / /run/docker/netns/abc123 rw shared:310 - nsfs nsfs rw\n",
+			want: "",
+		},
+		{
+			name:   "host bind mount of a container file elsewhere",
+			cgroup: "0::/\n",
+			mountinfo: "" +
+				"640 22 8:1 /var/lib/docker/containers/" + other + "/hostname /mnt/hostname rw,relatime - ext4 /dev/sda1 rw\n",
+			want: "",
+		},
+		{
+			name: "nothing readable",
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseSelfContainerID(tt.cgroup, tt.mountinfo); got != tt.want {
+				t.Errorf("parseSelfContainerID = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
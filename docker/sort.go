@@ -0,0 +1,74 @@
+package docker
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// SortKey selects the order of listed containers
+type SortKey string
+
+const (
+	// SortByNone keeps the order returned by the Docker daemon. This is the default.
+	SortByNone SortKey = ""
+	// SortByName sorts by container name
+	SortByName SortKey = "name"
+	// SortByCreated sorts by creation time, oldest first
+	SortByCreated SortKey = "created"
+	// SortByState sorts by state (created, exited, paused, running, ...) and then by name
+	SortByState SortKey = "state"
+)
+
+// ParseSortKey maps "name", "created" or "state" to a SortKey. An empty string
+// selects SortByNone.
+func ParseSortKey(key string) (SortKey, error) {
+	switch SortKey(strings.ToLower(strings.TrimSpace(key))) {
+	case SortByNone:
+		return SortByNone, nil
+	case SortByName:
+		return SortByName, nil
+	case SortByCreated:
+		return SortByCreated, nil
+	case SortByState:
+		return SortByState, nil
+	default:
+		return SortByNone, fmt.Errorf("unknown sort key: %q (expected name, created or state)", key)
+	}
+}
+
+// sortSummaries orders the listed containers by key. Ties are broken by name and
+// ID so the order is deterministic.
+func sortSummaries(containers []container.Summary, key SortKey) {
+	if key == SortByNone {
+		return
+	}
+
+	sort.SliceStable(containers, func(i, j int) bool {
+		a, b := containers[i], containers[j]
+		switch key {
+		case SortByCreated:
+			if a.Created != b.Created {
+				return a.Created < b.Created
+			}
+		case SortByState:
+			if a.State != b.State {
+				return a.State < b.State
+			}
+		}
+		if nameA, nameB := summaryName(a), summaryName(b); nameA != nameB {
+			return nameA < nameB
+		}
+		return a.ID < b.ID
+	})
+}
+
+// summaryName returns the container name without the leading slash
+func summaryName(c container.Summary) string {
+	if len(c.Names) == 0 {
+		return ""
+	}
+	return strings.TrimPrefix(c.Names[0], "/")
+}
@@ -0,0 +1,175 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrSSHNotFound is returned when an ssh:// Docker host is used but no ssh binary is installed
+var ErrSSHNotFound = errors.New("ssh binary not found in PATH")
+
+// sshHost is the placeholder host the Docker client addresses requests to when
+// the connection itself is made over SSH
+const sshHost = "http://docker.example.com"
+
+// sshCommand returns the ssh arguments that run "docker system dial-stdio" on the
+// host of an ssh://[user@]host[:port] URI, like the Docker CLI's SSH connection helper.
+// BatchMode makes authentication failures fail fast instead of prompting.
+func sshCommand(rawURL string) ([]string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ssh docker host %q: %w", rawURL, err)
+	}
+	if u.Scheme != "ssh" || u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid ssh docker host %q: expected ssh://[user@]host[:port]", rawURL)
+	}
+	if _, hasPassword := u.User.Password(); hasPassword {
+		return nil, fmt.Errorf("invalid ssh docker host %q: passwords are not supported, use an SSH key", rawURL)
+	}
+	if u.Path != "" && u.Path != "/" {
+		return nil, fmt.Errorf("invalid ssh docker host %q: paths are not supported", rawURL)
+	}
+
+	args := []string{"-o", "BatchMode=yes", "-o", "ConnectTimeout=10"}
+	if u.Port() != "" {
+		args = append(args, "-p", u.Port())
+	}
+	if user := u.User.Username(); user != "" {
+		args = append(args, "-l", user)
+	}
+	return append(args, "--", u.Hostname(), "docker", "system", "dial-stdio"), nil
+}
+
+// sshDialer returns a dial function for the Docker client that tunnels every
+// connection through a new ssh process
+func sshDialer(rawURL string) (func(ctx context.Context, network, addr string) (net.Conn, error), error) {
+	args, err := sshCommand(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	sshPath, err := exec.LookPath("ssh")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSSHNotFound, err)
+	}
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		// The process outlives the dial context, it is stopped when the connection is closed
+		cmd := exec.Command(sshPath, args...)
+		conn := &sshConn{cmd: cmd, host: rawURL}
+		cmd.Stderr = &conn.stderr
+		stdin, err := cmd.StdinPipe()
+		if err != nil {
+			return nil, err
+		}
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, err
+		}
+		conn.stdin, conn.stdout = stdin, stdout
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := cmd.Start(); err != nil {
+			return nil, fmt.Errorf("failed to start ssh to %s: %w", rawURL, err)
+		}
+		return conn, nil
+	}, nil
+}
+
+// sshConn is a net.Conn over the stdin and stdout of an ssh process
+type sshConn struct {
+	cmd    *exec.Cmd
+	host   string
+	stdin  io.WriteCloser
+	stdout io.ReadCloser
+	stderr lockedBuffer
+
+	closeOnce sync.Once
+}
+
+// Read reads from the remote dial-stdio process. Failures include ssh's own
+// output, which carries the reason for authentication or host key errors.
+func (c *sshConn) Read(p []byte) (int, error) {
+	n, err := c.stdout.Read(p)
+	if err != nil && err != io.EOF {
+		return n, c.wrap(err)
+	}
+	if err == io.EOF && n == 0 && c.stderr.Len() > 0 {
+		return n, c.wrap(err)
+	}
+	return n, err
+}
+
+// Write writes to the remote dial-stdio process
+func (c *sshConn) Write(p []byte) (int, error) {
+	n, err := c.stdin.Write(p)
+	if err != nil {
+		return n, c.wrap(err)
+	}
+	return n, nil
+}
+
+// Close stops the ssh process
+func (c *sshConn) Close() error {
+	c.closeOnce.Do(func() {
+		c.stdin.Close()
+		if c.cmd.Process != nil {
+			c.cmd.Process.Kill()
+		}
+		c.cmd.Wait()
+	})
+	return nil
+}
+
+// wrap adds ssh's stderr output to a connection error
+func (c *sshConn) wrap(err error) error {
+	if stderr := strings.TrimSpace(c.stderr.String()); stderr != "" {
+		return fmt.Errorf("ssh connection to %s failed: %s: %w", c.host, stderr, err)
+	}
+	return fmt.Errorf("ssh connection to %s failed: %w", c.host, err)
+}
+
+func (c *sshConn) LocalAddr() net.Addr                { return sshAddr{} }
+func (c *sshConn) RemoteAddr() net.Addr               { return sshAddr{} }
+func (c *sshConn) SetDeadline(t time.Time) error      { return nil }
+func (c *sshConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *sshConn) SetWriteDeadline(t time.Time) error { return nil }
+
+// sshAddr is the placeholder address of an sshConn
+type sshAddr struct{}
+
+func (sshAddr) Network() string { return "ssh" }
+func (sshAddr) String() string  { return "ssh" }
+
+// lockedBuffer is a bytes.Buffer safe for the concurrent writes of exec.Cmd and reads of sshConn
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func (b *lockedBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Len()
+}
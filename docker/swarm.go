@@ -0,0 +1,148 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/docker/docker/api/types/system"
+)
+
+var (
+	// ErrSwarmNotActive is returned when the daemon is not part of an active swarm
+	ErrSwarmNotActive = errors.New("docker daemon is not part of an active swarm")
+	// ErrSwarmNotManager is returned when the daemon is a swarm worker, which cannot list services
+	ErrSwarmNotManager = errors.New("docker daemon is not a swarm manager")
+)
+
+// Service represents a Docker Swarm service. Pangolin targets point at its
+// virtual IP or name, which load balances across the service tasks.
+type Service struct {
+	ID           string            `json:"id"`
+	Name         string            `json:"name"`
+	Image        string            `json:"image"`
+	Mode         string            `json:"mode"` // replicated, global, replicated-job or global-job
+	RunningTasks uint64            `json:"runningTasks"`
+	DesiredTasks uint64            `json:"desiredTasks"`
+	Ports        []ServicePort     `json:"ports,omitempty"`
+	VirtualIPs   []ServiceVIP      `json:"virtualIPs,omitempty"`
+	Labels       map[string]string `json:"labels,omitempty"`
+}
+
+// ServicePort is a port published by a swarm service
+type ServicePort struct {
+	TargetPort    int    `json:"targetPort"`
+	PublishedPort int    `json:"publishedPort,omitempty"`
+	Protocol      string `json:"protocol"`
+	PublishMode   string `json:"publishMode,omitempty"` // ingress or host
+}
+
+// ServiceVIP is the virtual IP of a service on an overlay network
+type ServiceVIP struct {
+	NetworkID string `json:"networkId"`
+	Addr      string `json:"addr"` // in CIDR notation, e.g. 10.0.1.5/24
+}
+
+// ListServices lists the swarm services of the daemon at socketPath, see Client.ListServices
+func ListServices(ctx context.Context, socketPath string, clientOpts ClientOptions, opts ListOptions) ([]Service, error) {
+	dockerClient, err := NewClientWithOptions(socketPath, clientOpts)
+	if err != nil {
+		return nil, err
+	}
+	defer dockerClient.Close()
+
+	return dockerClient.ListServices(ctx, opts)
+}
+
+// ListServices lists swarm services with their published ports and virtual IPs,
+// sorted by name. opts.LabelSelectors, Timeout and Retry are honored. It returns
+// ErrSwarmNotActive or ErrSwarmNotManager when the daemon cannot list services.
+func (d *Client) ListServices(ctx context.Context, opts ListOptions) ([]Service, error) {
+	info, err := withRetry(ctx, opts, "info", func(ctx context.Context) (system.Info, error) {
+		return d.cli.Info(ctx)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Docker info: %w", err)
+	}
+	if info.Swarm.LocalNodeState != swarm.LocalNodeStateActive {
+		return nil, ErrSwarmNotActive
+	}
+	if !info.Swarm.ControlAvailable {
+		return nil, ErrSwarmNotManager
+	}
+
+	serviceFilters := filters.NewArgs()
+	for _, selector := range opts.LabelSelectors {
+		selector = strings.TrimSpace(selector)
+		if selector != "" {
+			serviceFilters.Add("label", selector)
+		}
+	}
+
+	services, err := withRetry(ctx, opts, "service list", func(ctx context.Context) ([]swarm.Service, error) {
+		return d.cli.ServiceList(ctx, swarm.ServiceListOptions{Filters: serviceFilters, Status: true})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list services: %w", err)
+	}
+
+	var swarmServices []Service
+	for _, s := range services {
+		swarmService := Service{
+			ID:     s.ID,
+			Name:   s.Spec.Name,
+			Mode:   serviceMode(s.Spec.Mode),
+			Labels: s.Spec.Labels,
+		}
+		if s.Spec.TaskTemplate.ContainerSpec != nil {
+			swarmService.Image = s.Spec.TaskTemplate.ContainerSpec.Image
+		}
+		if s.ServiceStatus != nil {
+			swarmService.RunningTasks = s.ServiceStatus.RunningTasks
+			swarmService.DesiredTasks = s.ServiceStatus.DesiredTasks
+		}
+
+		for _, port := range s.Endpoint.Ports {
+			swarmService.Ports = append(swarmService.Ports, ServicePort{
+				TargetPort:    int(port.TargetPort),
+				PublishedPort: int(port.PublishedPort),
+				Protocol:      string(port.Protocol),
+				PublishMode:   string(port.PublishMode),
+			})
+		}
+		for _, vip := range s.Endpoint.VirtualIPs {
+			swarmService.VirtualIPs = append(swarmService.VirtualIPs, ServiceVIP{
+				NetworkID: vip.NetworkID,
+				Addr:      vip.Addr,
+			})
+		}
+
+		swarmServices = append(swarmServices, swarmService)
+	}
+
+	sort.Slice(swarmServices, func(i, j int) bool {
+		return swarmServices[i].Name < swarmServices[j].Name
+	})
+
+	return swarmServices, nil
+}
+
+// serviceMode returns a short name for the scheduling mode of a service
+func serviceMode(mode swarm.ServiceMode) string {
+	switch {
+	case mode.Replicated != nil:
+		return "replicated"
+	case mode.Global != nil:
+		return "global"
+	case mode.ReplicatedJob != nil:
+		return "replicated-job"
+	case mode.GlobalJob != nil:
+		return "global-job"
+	default:
+		return ""
+	}
+}
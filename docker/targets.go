@@ -0,0 +1,153 @@
+package docker
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/fosrl/newt/logger"
+)
+
+// TargetCallbacks receive target level changes derived from Docker events, see
+// WatchTargets. Either callback may be nil. They are called from a single
+// goroutine, in order.
+type TargetCallbacks struct {
+	// OnTargetAdded is called when a container becomes a valid target
+	OnTargetAdded func(Container)
+	// OnTargetRemoved is called with the last known state of a container that
+	// is no longer a valid target
+	OnTargetRemoved func(Container)
+}
+
+// WatchTargets reports target changes on the given socket, see Client.WatchTargets
+func WatchTargets(ctx context.Context, socketPath string, clientOpts ClientOptions, enforceNetworkValidation bool, opts ListOptions, callbacks TargetCallbacks) error {
+	dockerClient, err := NewClientWithOptions(socketPath, clientOpts)
+	if err != nil {
+		return err
+	}
+	defer dockerClient.Close()
+
+	return dockerClient.WatchTargets(ctx, enforceNetworkValidation, opts, callbacks)
+}
+
+// WatchTargets lists the containers once, calling OnTargetAdded for every valid
+// target, and then re-lists them on every container event, calling the callbacks
+// only for containers whose validity changed. This includes the resync after the
+// events stream reconnects, so changes missed while disconnected are reported
+// too. A container is a valid target when
+// it is listed with the options, is routable (see IsRoutable), has a resolvable
+// target address and its TargetTTL has not expired. A valid target whose address,
+// ports or networks change is reported as removed and added again. A target is
+// also removed once its TargetTTL expires, without waiting for an event. Failed
+// listings are logged and skipped. WatchTargets blocks until ctx is cancelled
+// and then returns its error.
+func (d *Client) WatchTargets(ctx context.Context, enforceNetworkValidation bool, opts ListOptions, callbacks TargetCallbacks) error {
+	// Subscribe first so no event between the initial listing and the subscription is missed
+	events := d.WatchContainers(ctx, opts)
+
+	current := make(map[string]Container)
+	refresh := func() {
+		containers, err := d.ListContainers(ctx, enforceNetworkValidation, opts)
+		if fatalListError(err) {
+			logger.WithFields(logger.Fields{"socketPath": d.socketPath}).Warn("Failed to list containers for target changes: %v", err)
+			return
+		}
+		current = applyTargetChanges(current, validTargets(containers), callbacks)
+	}
+
+	refresh()
+	for {
+		// Re-evaluate the current targets when the first TTL expires, no event announces it
+		var timer *time.Timer
+		var expiry <-chan time.Time
+		if expiresAt, ok := nextTargetExpiry(current); ok {
+			timer = time.NewTimer(max(expiresAt.Sub(clockNow()), 0))
+			expiry = timer.C
+		}
+
+		select {
+		case _, ok := <-events:
+			if !ok {
+				return ctx.Err()
+			}
+			refresh()
+		case <-expiry:
+			current = applyTargetChanges(current, validTargets(sortedTargets(current)), callbacks)
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// nextTargetExpiry returns the earliest TargetTTL expiry of the targets, false when none has a TTL
+func nextTargetExpiry(targets map[string]Container) (time.Time, bool) {
+	var next time.Time
+	found := false
+	for _, c := range targets {
+		if expiresAt, ok := c.targetExpiresAt(); ok && (!found || expiresAt.Before(next)) {
+			next, found = expiresAt, true
+		}
+	}
+	return next, found
+}
+
+// validTargets returns the containers that are valid targets now, see WatchTargets
+func validTargets(containers []Container) []Container {
+	now := clockNow()
+	var targets []Container
+	for _, c := range containers {
+		if !IsRoutable(c) || c.TargetExpired(now) {
+			continue
+		}
+		if _, _, err := (AddressResolver{}).resolveTargetAddress(c); err != nil {
+			continue
+		}
+		targets = append(targets, c)
+	}
+	return targets
+}
+
+// applyTargetChanges calls the callbacks for the differences between the current
+// targets and the new ones, and returns the new targets keyed like DiffContainers
+func applyTargetChanges(current map[string]Container, targets []Container, callbacks TargetCallbacks) map[string]Container {
+	next := make(map[string]Container, len(targets))
+	for _, c := range targets {
+		next[diffKey(c)] = c
+	}
+
+	diff := DiffContainers(sortedTargets(current), targets)
+	for _, c := range diff.Removed {
+		notifyTarget(callbacks.OnTargetRemoved, c)
+	}
+	for _, c := range diff.Changed {
+		notifyTarget(callbacks.OnTargetRemoved, current[diffKey(c)])
+		notifyTarget(callbacks.OnTargetAdded, c)
+	}
+	for _, c := range diff.Added {
+		notifyTarget(callbacks.OnTargetAdded, c)
+	}
+	return next
+}
+
+// sortedTargets returns the targets ordered by their key, so removals are reported in a stable order
+func sortedTargets(targets map[string]Container) []Container {
+	keys := make([]string, 0, len(targets))
+	for key := range targets {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	sorted := make([]Container, len(keys))
+	for i, key := range keys {
+		sorted[i] = targets[key]
+	}
+	return sorted
+}
+
+// notifyTarget calls the callback if it is set
+func notifyTarget(callback func(Container), c Container) {
+	if callback != nil {
+		callback(c)
+	}
+}
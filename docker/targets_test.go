@@ -0,0 +1,54 @@
+package docker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextTargetExpiry(t *testing.T) {
+	started := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	if _, ok := nextTargetExpiry(map[string]Container{"a": {ID: "a", StartedAt: started}}); ok {
+		t.Errorf("nextTargetExpiry without TTLs reported an expiry")
+	}
+
+	targets := map[string]Container{
+		"a": {ID: "a", StartedAt: started, TargetTTL: time.Hour},
+		"b": {ID: "b", StartedAt: started, TargetTTL: 30 * time.Minute},
+		"c": {ID: "c", StartedAt: started},
+		// Without a start time the TTL counts from creation
+		"d": {ID: "d", Created: started.Add(-time.Hour).Unix(), TargetTTL: 45 * time.Minute},
+	}
+	got, ok := nextTargetExpiry(targets)
+	if want := started.Add(-15 * time.Minute); !ok || !got.Equal(want) {
+		t.Errorf("nextTargetExpiry = %v, %t, want %v", got, ok, want)
+	}
+}
+
+func TestApplyTargetChangesRemovesExpiredTargets(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	SetClock(func() time.Time { return now })
+	defer SetClock(nil)
+
+	target := Container{
+		ID:            "a",
+		Name:          "job",
+		StartedAt:     now.Add(-time.Minute),
+		TargetTTL:     time.Hour,
+		TargetAddress: "job",
+		Networks:      map[string]Network{"ci": {IPAddress: "172.20.0.5"}},
+	}
+
+	var removed []string
+	callbacks := TargetCallbacks{OnTargetRemoved: func(c Container) { removed = append(removed, c.ID) }}
+	current := applyTargetChanges(nil, validTargets([]Container{target}), callbacks)
+	if len(current) != 1 {
+		t.Fatalf("valid target was not added: %v", current)
+	}
+
+	now = now.Add(time.Hour)
+	current = applyTargetChanges(current, validTargets(sortedTargets(current)), callbacks)
+	if len(current) != 0 || len(removed) != 1 {
+		t.Errorf("expired target was not removed: current %v, removed %v", current, removed)
+	}
+}
@@ -0,0 +1,68 @@
+package docker
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TargetNameLabel derives the target hostname from a template such as
+// "${compose.service}.internal". It is applied when TargetAddressLabel is unset.
+const TargetNameLabel = "newt.name"
+
+// templatePlaceholders maps the placeholders supported by TargetNameLabel to their values
+var templatePlaceholders = map[string]func(c Container) string{
+	"container.name":  func(c Container) string { return c.Name },
+	"compose.project": func(c Container) string { return c.ComposeProject },
+	"compose.service": func(c Container) string { return c.ComposeService },
+	"network.alias":   func(c Container) string { return addressOfKind(c, AddressKindAlias) },
+}
+
+// labelTargetName renders TargetNameLabel for the container. It returns an empty
+// string when the label is unset and an error when the template uses an unknown
+// or empty placeholder or does not render to a valid hostname.
+func labelTargetName(c Container) (string, error) {
+	template, ok := c.Labels[TargetNameLabel]
+	if !ok {
+		return "", nil
+	}
+
+	name, err := renderTargetName(strings.TrimSpace(template), c)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s label %q: %w", TargetNameLabel, template, err)
+	}
+	if !validHostname(name) {
+		return "", fmt.Errorf("invalid %s label %q: %q is not a valid hostname", TargetNameLabel, template, name)
+	}
+	return name, nil
+}
+
+// renderTargetName substitutes the ${...} placeholders of the template. Only the
+// keys of templatePlaceholders are supported.
+func renderTargetName(template string, c Container) (string, error) {
+	var rendered strings.Builder
+	for {
+		start := strings.Index(template, "${")
+		if start < 0 {
+			rendered.WriteString(template)
+			return rendered.String(), nil
+		}
+		end := strings.Index(template[start:], "}")
+		if end < 0 {
+			return "", fmt.Errorf("unterminated placeholder")
+		}
+
+		key := strings.TrimSpace(template[start+2 : start+end])
+		value, ok := templatePlaceholders[key]
+		if !ok {
+			return "", fmt.Errorf("unknown placeholder ${%s} (expected container.name, compose.project, compose.service or network.alias)", key)
+		}
+		resolved := value(c)
+		if resolved == "" {
+			return "", fmt.Errorf("placeholder ${%s} is empty for container %s", key, c.Name)
+		}
+
+		rendered.WriteString(template[:start])
+		rendered.WriteString(resolved)
+		template = template[start+end+1:]
+	}
+}
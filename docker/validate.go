@@ -0,0 +1,114 @@
+package docker
+
+import (
+	"context"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// Target is an address and inclusive port range to validate, see ValidateTargets
+type Target struct {
+	Address   string
+	StartPort int
+	EndPort   int // 0 validates StartPort only
+}
+
+// endPort returns the last port of the range
+func (t Target) endPort() int {
+	if t.EndPort == 0 {
+		return t.StartPort
+	}
+	return t.EndPort
+}
+
+// Result is the outcome of validating a single Target. Container and Port are
+// set for valid targets, see MatchContainerWithOptions, and Err explains why
+// the others are invalid.
+type Result struct {
+	Target    Target
+	Valid     bool
+	Container *Container
+	Port      *Port
+	Err       error
+}
+
+// ValidateTargets validates several targets on the given socket, see Client.ValidateTargets
+func ValidateTargets(ctx context.Context, socketPath string, clientOpts ClientOptions, targets []Target) ([]Result, error) {
+	dockerClient, err := NewClientWithOptions(socketPath, clientOpts)
+	if err != nil {
+		return nil, err
+	}
+	defer dockerClient.Close()
+
+	return dockerClient.ValidateTargets(ctx, targets, ValidationOptions{})
+}
+
+// ValidateTargets validates every target like IsWithinHostNetworkWithOptions, but
+// against a single snapshot of the daemon's containers, so loading a configuration
+// with many targets lists the containers once instead of once per target. Results
+// are in target order. An error is only returned when the daemon can't be listed.
+func (d *Client) ValidateTargets(ctx context.Context, targets []Target, opts ValidationOptions) ([]Result, error) {
+	snap := d.newValidationSnapshot(opts)
+	if _, err := snap.allContainers(ctx); fatalListError(err) {
+		return nil, err
+	}
+
+	results := make([]Result, len(targets))
+	for i, target := range targets {
+		c, port, err := d.matchWithSnapshot(ctx, snap, target.Address, target.StartPort, target.endPort(), opts)
+		results[i] = Result{Target: target, Valid: err == nil, Container: c, Port: port, Err: err}
+	}
+	return results, nil
+}
+
+// validationSnapshot lazily loads the listings target validation matches against
+// and keeps them, so validating several targets costs every listing at most once.
+// It is not safe for concurrent use.
+type validationSnapshot struct {
+	d    *Client
+	opts ValidationOptions
+
+	networkLoaded bool
+	network       []Container
+	networkErr    error
+
+	allLoaded bool
+	all       []Container
+	allErr    error
+
+	hostLoaded bool
+	host       *container.InspectResponse
+	hostErr    error
+}
+
+// newValidationSnapshot creates an empty snapshot of the client's daemon
+func (d *Client) newValidationSnapshot(opts ValidationOptions) *validationSnapshot {
+	return &validationSnapshot{d: d, opts: opts}
+}
+
+// networkContainers returns the containers on Newt's networks, with network validation enforced
+func (s *validationSnapshot) networkContainers(ctx context.Context) ([]Container, error) {
+	if !s.networkLoaded {
+		s.network, s.networkErr = s.d.ListContainers(ctx, true, ListOptions{Networks: s.opts.Networks, ExcludePorts: s.opts.ExcludePorts})
+		s.networkLoaded = true
+	}
+	return s.network, s.networkErr
+}
+
+// allContainers returns every container regardless of Newt's networks
+func (s *validationSnapshot) allContainers(ctx context.Context) ([]Container, error) {
+	if !s.allLoaded {
+		s.all, s.allErr = s.d.ListContainers(ctx, false, ListOptions{ExcludePorts: s.opts.ExcludePorts})
+		s.allLoaded = true
+	}
+	return s.all, s.allErr
+}
+
+// hostContainer returns Newt's own container, see getHostContainer
+func (s *validationSnapshot) hostContainer(ctx context.Context) (*container.InspectResponse, error) {
+	if !s.hostLoaded {
+		s.host, s.hostErr = getHostContainer(ctx, s.d.cli)
+		s.hostLoaded = true
+	}
+	return s.host, s.hostErr
+}
@@ -0,0 +1,93 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/versions"
+	"github.com/fosrl/newt/logger"
+)
+
+// apiVersionPattern matches Docker API versions such as 1.44
+var apiVersionPattern = regexp.MustCompile(`^\d+\.\d+$`)
+
+// EngineVersion describes the Docker Engine a client is connected to
+type EngineVersion struct {
+	Version       string `json:"version"`       // engine version, e.g. 28.3.3
+	APIVersion    string `json:"apiVersion"`    // highest API version supported by the daemon
+	MinAPIVersion string `json:"minApiVersion"` // lowest API version supported by the daemon
+	ClientVersion string `json:"clientVersion"` // API version negotiated by the client
+	Os            string `json:"os"`
+	Arch          string `json:"arch"`
+}
+
+// String returns a short human readable description of the engine version
+func (v EngineVersion) String() string {
+	return fmt.Sprintf("Docker Engine %s (API %s, negotiated %s, %s/%s)", v.Version, v.APIVersion, v.ClientVersion, v.Os, v.Arch)
+}
+
+// ServerVersion returns the version of the Docker Engine behind the socket
+func ServerVersion(ctx context.Context, socketPath string, clientOpts ClientOptions) (EngineVersion, error) {
+	dockerClient, err := NewClientWithOptions(socketPath, clientOpts)
+	if err != nil {
+		return EngineVersion{}, err
+	}
+	defer dockerClient.Close()
+
+	return dockerClient.ServerVersion(ctx)
+}
+
+// ServerVersion returns the version of the Docker Engine the client is connected
+// to. The first successful call per client is logged at Info level.
+func (d *Client) ServerVersion(ctx context.Context) (EngineVersion, error) {
+	version, err := withRetry(ctx, ListOptions{}, "server version", func(ctx context.Context) (types.Version, error) {
+		return d.cli.ServerVersion(ctx)
+	})
+	if err != nil {
+		return EngineVersion{}, fmt.Errorf("failed to get Docker server version: %w", err)
+	}
+
+	engineVersion := EngineVersion{
+		Version:       version.Version,
+		APIVersion:    version.APIVersion,
+		MinAPIVersion: version.MinAPIVersion,
+		ClientVersion: d.cli.ClientVersion(),
+		Os:            version.Os,
+		Arch:          version.Arch,
+	}
+
+	d.versionLogged.Do(func() {
+		logger.WithFields(logger.Fields{"socketPath": d.socketPath}).Info("Connected to %s", engineVersion)
+	})
+
+	return engineVersion, nil
+}
+
+// checkAPIVersion returns an ErrAPIVersionTooOld error when the negotiated API
+// version is below the client's minimum. The comparison is done once per client;
+// failures to reach the daemon are returned without being remembered.
+func (d *Client) checkAPIVersion(ctx context.Context) error {
+	if d.minAPIVersion == "" {
+		return nil
+	}
+
+	d.apiCheckMu.Lock()
+	defer d.apiCheckMu.Unlock()
+	if d.apiChecked {
+		return d.apiErr
+	}
+
+	// Asking for the version also negotiates it and logs it once
+	version, err := d.ServerVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if versions.LessThan(version.ClientVersion, d.minAPIVersion) {
+		d.apiErr = fmt.Errorf("%w: %s negotiated API %s, but at least %s is required, please upgrade Docker",
+			ErrAPIVersionTooOld, d.socketPath, version.ClientVersion, d.minAPIVersion)
+	}
+	d.apiChecked = true
+	return d.apiErr
+}
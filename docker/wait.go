@@ -0,0 +1,50 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/fosrl/newt/logger"
+)
+
+// DefaultSocketPollInterval is how often WaitForSocket checks the socket
+const DefaultSocketPollInterval = 500 * time.Millisecond
+
+// WaitForSocket blocks until the Docker socket accepts connections, e.g. when Newt
+// starts before the daemon. It returns nil once the socket is ready and an error
+// wrapping the last check failure when timeout expires or ctx is cancelled.
+func WaitForSocket(ctx context.Context, socketPath string, timeout time.Duration) error {
+	return WaitForSocketWithInterval(ctx, socketPath, timeout, DefaultSocketPollInterval)
+}
+
+// WaitForSocketWithInterval is WaitForSocket with an explicit poll interval. A
+// non-positive interval selects DefaultSocketPollInterval.
+func WaitForSocketWithInterval(ctx context.Context, socketPath string, timeout time.Duration, interval time.Duration) error {
+	if interval <= 0 {
+		interval = DefaultSocketPollInterval
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		available, err := CheckSocketWithError(ctx, socketPath)
+		if available {
+			return nil
+		}
+		logger.WithFields(logger.Fields{"socketPath": socketPath}).Debug("Waiting for Docker socket: %v", err)
+
+		select {
+		case <-ctx.Done():
+			if err == nil {
+				err = ctx.Err()
+			}
+			return fmt.Errorf("docker socket %s not ready after %s: %w", socketPath, timeout, err)
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fosrl/newt/docker"
+	"github.com/fosrl/newt/logger"
+)
+
+// Exit codes of the docker subcommand
+const (
+	dockerExitOK         = 0
+	dockerExitFailure    = 1 // no daemon could be listed
+	dockerExitUsage      = 2
+	dockerExitPartial    = 3 // some daemons failed, the others were listed
+	dockerExitIncomplete = 4 // every daemon was listed, but some containers could not be inspected
+)
+
+// runDockerCommand runs `newt docker <command>` and returns the exit code. It
+// only talks to the Docker daemons, so it works without Pangolin connectivity.
+func runDockerCommand(args []string) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		fmt.Fprintln(os.Stderr, "Usage: newt docker list [flags]")
+		if len(args) == 0 {
+			return dockerExitUsage
+		}
+		return dockerExitOK
+	}
+
+	switch args[0] {
+	case "list":
+		return runDockerList(args[1:], os.Stdout, os.Stderr)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown docker command: %s\nUsage: newt docker list [flags]\n", args[0])
+		return dockerExitUsage
+	}
+}
+
+// runDockerList prints the containers Newt discovers with the same options as
+// the main command, read from the same environment variables by default
+func runDockerList(args []string, stdout io.Writer, stderr io.Writer) int {
+	fs := flag.NewFlagSet("newt docker list", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	socket := fs.String("socket", os.Getenv("DOCKER_SOCKET"), "Comma separated Docker sockets or host URIs, the detected socket is used when empty")
+	dockerCtxName := fs.String("context", os.Getenv("DOCKER_CONTEXT"), "Docker context whose endpoint is used when --socket is empty")
+	jsonOutput := fs.Bool("json", false, "Print the discovered containers as JSON")
+	enforce := fs.Bool("enforce-network-validation", envBool("DOCKER_ENFORCE_NETWORK_VALIDATION"), "Only list containers on a network shared with Newt")
+	labelFilter := fs.String("label-filter", os.Getenv("DOCKER_LABEL_FILTER"), "Comma separated container labels (key or key=value) required for discovery")
+	excludeLabels := fs.String("exclude-labels", envOr("DOCKER_EXCLUDE_LABELS", docker.DisableLabelSelector), "Comma separated container labels (key or key=value) that opt a container out of discovery")
+	excludeImages := fs.String("exclude-images", envOr("DOCKER_EXCLUDE_IMAGES", "fosrl/newt"), "Comma separated image prefixes to never discover (Newt itself by default)")
+	portLabels := fs.String("port-labels", os.Getenv("DOCKER_PORT_LABELS"), "Comma separated label keys (with * wildcards) that declare a container's port")
+	networks := fs.String("networks", os.Getenv("DOCKER_NETWORKS"), "Comma separated networks to restrict container discovery to")
+	names := fs.String("names", os.Getenv("DOCKER_NAMES"), "Comma separated container name patterns to restrict container discovery to")
+	newtNetwork := fs.String("newt-network", os.Getenv("DOCKER_NEWT_NETWORK"), "Only discover containers on this network of Newt's container")
+	excludePorts := fs.String("exclude-ports", os.Getenv("DOCKER_EXCLUDE_PORTS"), "Comma separated ports or port ranges that are never advertised")
+	envPrefix := fs.String("env-prefix", os.Getenv("DOCKER_ENV_PREFIX"), "Include container environment variables starting with this prefix")
+	labelKeys := fs.String("label-keys", os.Getenv("DOCKER_LABEL_KEYS"), "Comma separated label keys (with * wildcards) of the labels to include")
+	addressMode := fs.String("address-mode", os.Getenv("DOCKER_ADDRESS_MODE"), "Target address mode: auto, ip or hostname")
+	addressFamily := fs.String("address-family", os.Getenv("DOCKER_ADDRESS_FAMILY"), "Target address family: ipv4, ipv6 or dualstack")
+	preferUserNetworks := fs.Bool("prefer-user-networks", envBool("DOCKER_PREFER_USER_NETWORKS"), "Prefer user-defined networks over the default bridge for target addresses")
+	minAPIVersion := fs.String("min-api-version", os.Getenv("DOCKER_MIN_API_VERSION"), "Minimum Docker API version the daemons must support")
+	includeStopped := fs.Bool("all", false, "Include stopped containers")
+	timeout := fs.Duration("timeout", 30*time.Second, "Time allowed for listing all daemons")
+	logLevelValue := fs.String("log-level", envOr("LOG_LEVEL", "WARN"), "Log level of the messages printed to stderr")
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return dockerExitOK
+		}
+		return dockerExitUsage
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(stderr, "Unexpected arguments: %s\n", strings.Join(fs.Args(), " "))
+		return dockerExitUsage
+	}
+
+	// Keep stdout for the listing so it can be piped, e.g. to jq
+	logger.Init()
+	logger.GetLogger().SetOutput(stderr)
+	logger.GetLogger().SetLevel(parseLogLevel(*logLevelValue))
+
+	cfg := docker.Config{
+		SocketPath:               *socket,
+		Context:                  *dockerCtxName,
+		MinAPIVersion:            *minAPIVersion,
+		EnforceNetworkValidation: *enforce,
+		LabelSelectors:           splitList(*labelFilter),
+		ExcludeLabelSelectors:    splitList(*excludeLabels),
+		ExcludeImages:            splitList(*excludeImages),
+		PortLabels:               splitList(*portLabels),
+		Networks:                 splitList(*networks),
+		Names:                    splitList(*names),
+		NewtNetwork:              *newtNetwork,
+		PreferUserNetworks:       *preferUserNetworks,
+		EnvPrefix:                *envPrefix,
+		LabelKeys:                splitList(*labelKeys),
+	}
+
+	var err error
+	if cfg.AddressMode, err = docker.ParseAddressMode(*addressMode); err != nil {
+		fmt.Fprintln(stderr, err)
+		return dockerExitUsage
+	}
+	if cfg.AddressFamily, err = docker.ParseAddressFamily(*addressFamily); err != nil {
+		fmt.Fprintln(stderr, err)
+		return dockerExitUsage
+	}
+	if cfg.ExcludePorts, err = docker.ParsePortRanges(*excludePorts); err != nil {
+		fmt.Fprintln(stderr, err)
+		return dockerExitUsage
+	}
+	if dockerTLSCA := os.Getenv("DOCKER_TLS_CA"); dockerTLSCA != "" || os.Getenv("DOCKER_TLS_CERT") != "" || os.Getenv("DOCKER_TLS_KEY") != "" {
+		cfg.TLS = &docker.TLSConfig{
+			CAFile:   dockerTLSCA,
+			CertFile: os.Getenv("DOCKER_TLS_CERT"),
+			KeyFile:  os.Getenv("DOCKER_TLS_KEY"),
+		}
+	}
+
+	clients, err := docker.NewClientsFromConfig(cfg)
+	for _, dockerClient := range clients {
+		defer dockerClient.Close()
+	}
+	if len(clients) == 0 {
+		fmt.Fprintf(stderr, "Failed to create Docker client: %v\n", err)
+		return dockerExitFailure
+	}
+	failed := err != nil
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to create Docker client: %v\n", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	opts := cfg.ListOptions()
+	opts.IncludeStopped = *includeStopped
+
+	// List the daemons one by one, ListContainersFromClients only logs daemons that are down
+	containers := []docker.Container{}
+	listed, incomplete := 0, false
+	for _, dockerClient := range clients {
+		listing, err := dockerClient.ListContainers(ctx, cfg.EnforceNetworkValidation, opts)
+		switch {
+		case errors.Is(err, docker.ErrPartialResults):
+			fmt.Fprintf(stderr, "Some containers on %s were listed without inspect details: %v\n", dockerClient.SocketPath(), err)
+			incomplete = true
+		case err != nil:
+			fmt.Fprintf(stderr, "Failed to list containers on %s: %v\n", dockerClient.SocketPath(), err)
+			failed = true
+			continue
+		}
+		listed++
+		containers = append(containers, listing...)
+	}
+	if listed == 0 {
+		return dockerExitFailure
+	}
+
+	if *jsonOutput {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(containers); err != nil {
+			fmt.Fprintf(stderr, "Failed to encode containers: %v\n", err)
+			return dockerExitFailure
+		}
+	} else {
+		printContainerTable(stdout, containers)
+	}
+
+	switch {
+	case failed:
+		return dockerExitPartial
+	case incomplete:
+		return dockerExitIncomplete
+	}
+	return dockerExitOK
+}
+
+// printContainerTable prints one line per container with its target
+func printContainerTable(w io.Writer, containers []docker.Container) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tSTATE\tTARGET\tPORTS\tSOCKET")
+	for _, c := range containers {
+		ports := make([]string, len(c.Ports))
+		for i, port := range c.Ports {
+			ports[i] = strconv.Itoa(port.PrivatePort) + "/" + port.Type
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", c.ID, c.Name, c.State, c.TargetAddress, strings.Join(ports, ","), c.SourceSocket)
+	}
+	tw.Flush()
+}
+
+// envBool reads a boolean environment variable, false when unset or invalid
+func envBool(key string) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	return err == nil && value
+}
+
+// envOr reads an environment variable, falling back to def when unset
+func envOr(key string, def string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return def
+}
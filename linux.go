@@ -5,7 +5,9 @@ package main
 import (
 	"fmt"
 	"os"
+	"os/signal"
 	"runtime"
+	"syscall"
 
 	"github.com/fosrl/newt/logger"
 	"github.com/fosrl/newt/proxy"
@@ -72,3 +74,19 @@ func clientsAddProxyTargetNative(pm *proxy.ProxyManager, tunnelIp string) {
 		pm.AddTarget("udp", tunnelIp, int(wgServiceNative.Port), fmt.Sprintf("127.0.0.1:%d", wgServiceNative.Port))
 	}
 }
+
+// setupLogLevelToggle switches between the configured log level and DEBUG on SIGUSR1
+func setupLogLevelToggle(configured logger.LogLevel) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGUSR1)
+	go func() {
+		for range sigCh {
+			if logger.GetLevel() == logger.DEBUG {
+				logger.SetLevel(configured)
+			} else {
+				logger.SetLevel(logger.DEBUG)
+			}
+			logger.Info("Log level changed to %s", logger.GetLevel())
+		}
+	}()
+}
@@ -0,0 +1,102 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+type LogFormat int
+
+const (
+	FormatText LogFormat = iota
+	FormatJSON
+)
+
+// Fields are structured key/value pairs attached to a log entry
+type Fields map[string]interface{}
+
+// ParseFormat maps a format name ("text" or "json") to a LogFormat, defaulting to text
+func ParseFormat(format string) LogFormat {
+	if strings.EqualFold(strings.TrimSpace(format), "json") {
+		return FormatJSON
+	}
+	return FormatText
+}
+
+// formatText renders an entry as a human readable line with trailing key=value fields
+func formatText(level LogLevel, timestamp time.Time, message string, fields Fields) string {
+	line := fmt.Sprintf("%s: %s %s", level.String(), timestamp.Format("2006/01/02 15:04:05"), message)
+	for _, key := range sortedKeys(fields) {
+		line += fmt.Sprintf(" %s=%v", key, fields[key])
+	}
+	return line
+}
+
+// formatJSON renders an entry as a single JSON object. Fields never override the
+// level, time and msg keys.
+func formatJSON(level LogLevel, timestamp time.Time, message string, fields Fields) string {
+	entry := make(map[string]interface{}, len(fields)+3)
+	for key, value := range fields {
+		if err, ok := value.(error); ok {
+			value = err.Error()
+		}
+		entry[key] = value
+	}
+	entry["level"] = level.String()
+	entry["time"] = timestamp.Format(time.RFC3339)
+	entry["msg"] = message
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Sprintf(`{"level":%q,"time":%q,"msg":%q}`, level.String(), timestamp.Format(time.RFC3339), message)
+	}
+	return string(data)
+}
+
+func sortedKeys(fields Fields) []string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Entry is a log entry with structured fields
+type Entry struct {
+	logger *Logger
+	fields Fields
+}
+
+// WithFields returns an entry that attaches the fields to every message it logs
+func (l *Logger) WithFields(fields Fields) *Entry {
+	return &Entry{logger: l, fields: fields}
+}
+
+// WithFields returns an entry on the default logger with the given fields
+func WithFields(fields Fields) *Entry {
+	return GetLogger().WithFields(fields)
+}
+
+// Debug logs debug level messages
+func (e *Entry) Debug(format string, args ...interface{}) {
+	e.logger.logFields(DEBUG, e.fields, format, args...)
+}
+
+// Info logs info level messages
+func (e *Entry) Info(format string, args ...interface{}) {
+	e.logger.logFields(INFO, e.fields, format, args...)
+}
+
+// Warn logs warning level messages
+func (e *Entry) Warn(format string, args ...interface{}) {
+	e.logger.logFields(WARN, e.fields, format, args...)
+}
+
+// Error logs error level messages
+func (e *Entry) Error(format string, args ...interface{}) {
+	e.logger.logFields(ERROR, e.fields, format, args...)
+}
@@ -1,5 +1,10 @@
 package logger
 
+import (
+	"fmt"
+	"strings"
+)
+
 type LogLevel int
 
 const (
@@ -25,3 +30,18 @@ func (l LogLevel) String() string {
 	}
 	return "UNKNOWN"
 }
+
+// ParseLevel maps a level name such as "debug", "info", "warn" or "error" to a
+// LogLevel. It is case insensitive and returns an error for unknown names.
+func ParseLevel(level string) (LogLevel, error) {
+	name := strings.ToUpper(strings.TrimSpace(level))
+	if name == "WARNING" {
+		name = "WARN"
+	}
+	for l, s := range levelStrings {
+		if s == name {
+			return l, nil
+		}
+	}
+	return INFO, fmt.Errorf("unknown log level: %q", level)
+}
@@ -6,13 +6,15 @@ import (
 	"log"
 	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // Logger struct holds the logger instance
 type Logger struct {
 	logger *log.Logger
-	level  LogLevel
+	level  atomic.Int32 // LogLevel, atomic so it can be changed at runtime
+	format atomic.Int32 // LogFormat
 }
 
 var (
@@ -22,10 +24,11 @@ var (
 
 // NewLogger creates a new logger instance
 func NewLogger() *Logger {
-	return &Logger{
+	l := &Logger{
 		logger: log.New(os.Stdout, "", 0),
-		level:  DEBUG,
 	}
+	l.level.Store(int32(DEBUG))
+	return l
 }
 
 // Init initializes the default logger
@@ -44,9 +47,24 @@ func GetLogger() *Logger {
 	return defaultLogger
 }
 
-// SetLevel sets the minimum logging level
+// SetLevel sets the minimum logging level. It is safe to call while logging.
 func (l *Logger) SetLevel(level LogLevel) {
-	l.level = level
+	l.level.Store(int32(level))
+}
+
+// GetLevel returns the current minimum logging level
+func (l *Logger) GetLevel() LogLevel {
+	return LogLevel(l.level.Load())
+}
+
+// Enabled reports whether messages at the given level are logged
+func (l *Logger) Enabled(level LogLevel) bool {
+	return level >= l.GetLevel()
+}
+
+// SetFormat sets the output format (FormatText or FormatJSON)
+func (l *Logger) SetFormat(format LogFormat) {
+	l.format.Store(int32(format))
 }
 
 // SetOutput sets the output destination for the logger
@@ -56,7 +74,12 @@ func (l *Logger) SetOutput(w io.Writer) {
 
 // log handles the actual logging
 func (l *Logger) log(level LogLevel, format string, args ...interface{}) {
-	if level < l.level {
+	l.logFields(level, nil, format, args...)
+}
+
+// logFields handles logging with structured fields
+func (l *Logger) logFields(level LogLevel, fields Fields, format string, args ...interface{}) {
+	if !l.Enabled(level) {
 		return
 	}
 
@@ -75,9 +98,13 @@ func (l *Logger) log(level LogLevel, format string, args ...interface{}) {
 		location = time.Local
 	}
 
-	timestamp := time.Now().In(location).Format("2006/01/02 15:04:05")
+	timestamp := time.Now().In(location)
 	message := fmt.Sprintf(format, args...)
-	l.logger.Printf("%s: %s %s", level.String(), timestamp, message)
+	if LogFormat(l.format.Load()) == FormatJSON {
+		l.logger.Print(formatJSON(level, timestamp, message, fields))
+	} else {
+		l.logger.Print(formatText(level, timestamp, message, fields))
+	}
 }
 
 // Debug logs debug level messages
@@ -127,6 +154,26 @@ func Fatal(format string, args ...interface{}) {
 	GetLogger().Fatal(format, args...)
 }
 
+// SetLevel sets the minimum logging level of the default logger
+func SetLevel(level LogLevel) {
+	GetLogger().SetLevel(level)
+}
+
+// GetLevel returns the minimum logging level of the default logger
+func GetLevel() LogLevel {
+	return GetLogger().GetLevel()
+}
+
+// Enabled reports whether the default logger logs messages at the given level
+func Enabled(level LogLevel) bool {
+	return GetLogger().Enabled(level)
+}
+
+// SetFormat sets the output format of the default logger
+func SetFormat(format LogFormat) {
+	GetLogger().SetFormat(format)
+}
+
 // SetOutput sets the output destination for the default logger
 func SetOutput(w io.Writer) {
 	GetLogger().SetOutput(w)
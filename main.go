@@ -1,7 +1,9 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"net"
@@ -96,14 +98,38 @@ var (
 	privateKey                         wgtypes.Key
 	err                                error
 	logLevel                           string
+	logFormat                          string
 	interfaceName                      string
 	generateAndSaveKeyTo               string
 	keepInterface                      bool
 	acceptClients                      bool
 	updownScript                       string
 	dockerSocket                       string
+	dockerContext                      string
 	dockerEnforceNetworkValidation     string
 	dockerEnforceNetworkValidationBool bool
+	dockerTLSCA                        string
+	dockerTLSCert                      string
+	dockerTLSKey                       string
+	dockerLabelFilter                  string
+	dockerExcludeLabels                string
+	dockerExcludeImages                string
+	dockerPortLabels                   string
+	dockerNetworks                     string
+	dockerExcludePorts                 string
+	dockerEnvPrefix                    string
+	dockerLabelKeys                    string
+	dockerNames                        string
+	dockerMinAPIVersion                string
+	dockerNewtNetwork                  string
+	dockerAddressMode                  string
+	dockerClients                      []*docker.Client
+	dockerConfig                       docker.Config
+	dockerAddressModeValue             docker.AddressMode
+	dockerAddressFamily                string
+	dockerPreferUserNetworks           string
+	dockerPreferUserNetworksBool       bool
+	dockerAddressFamilyValue           docker.AddressFamily
 	pingInterval                       time.Duration
 	pingTimeout                        time.Duration
 	publicKey                          wgtypes.Key
@@ -126,6 +152,11 @@ var (
 )
 
 func main() {
+	// `newt docker ...` inspects container discovery without connecting to Pangolin
+	if len(os.Args) > 1 && os.Args[1] == "docker" {
+		os.Exit(runDockerCommand(os.Args[2:]))
+	}
+
 	// if PANGOLIN_ENDPOINT, NEWT_ID, and NEWT_SECRET are set as environment variables, they will be used as default values
 	endpoint = os.Getenv("PANGOLIN_ENDPOINT")
 	id = os.Getenv("NEWT_ID")
@@ -133,6 +164,7 @@ func main() {
 	mtu = os.Getenv("MTU")
 	dns = os.Getenv("DNS")
 	logLevel = os.Getenv("LOG_LEVEL")
+	logFormat = os.Getenv("LOG_FORMAT")
 	updownScript = os.Getenv("UPDOWN_SCRIPT")
 	interfaceName = os.Getenv("INTERFACE")
 	generateAndSaveKeyTo = os.Getenv("GENERATE_AND_SAVE_KEY_TO")
@@ -147,9 +179,27 @@ func main() {
 	enforceHealthcheckCert = enforceHealthcheckCertEnv == "true"
 
 	dockerSocket = os.Getenv("DOCKER_SOCKET")
+	dockerContext = os.Getenv("DOCKER_CONTEXT")
 	pingIntervalStr := os.Getenv("PING_INTERVAL")
 	pingTimeoutStr := os.Getenv("PING_TIMEOUT")
 	dockerEnforceNetworkValidation = os.Getenv("DOCKER_ENFORCE_NETWORK_VALIDATION")
+	dockerTLSCA = os.Getenv("DOCKER_TLS_CA")
+	dockerTLSCert = os.Getenv("DOCKER_TLS_CERT")
+	dockerTLSKey = os.Getenv("DOCKER_TLS_KEY")
+	dockerLabelFilter = os.Getenv("DOCKER_LABEL_FILTER")
+	dockerExcludeLabels = os.Getenv("DOCKER_EXCLUDE_LABELS")
+	dockerExcludeImages = os.Getenv("DOCKER_EXCLUDE_IMAGES")
+	dockerPortLabels = os.Getenv("DOCKER_PORT_LABELS")
+	dockerNetworks = os.Getenv("DOCKER_NETWORKS")
+	dockerExcludePorts = os.Getenv("DOCKER_EXCLUDE_PORTS")
+	dockerEnvPrefix = os.Getenv("DOCKER_ENV_PREFIX")
+	dockerLabelKeys = os.Getenv("DOCKER_LABEL_KEYS")
+	dockerNames = os.Getenv("DOCKER_NAMES")
+	dockerMinAPIVersion = os.Getenv("DOCKER_MIN_API_VERSION")
+	dockerNewtNetwork = os.Getenv("DOCKER_NEWT_NETWORK")
+	dockerAddressMode = os.Getenv("DOCKER_ADDRESS_MODE")
+	dockerAddressFamily = os.Getenv("DOCKER_ADDRESS_FAMILY")
+	dockerPreferUserNetworks = os.Getenv("DOCKER_PREFER_USER_NETWORKS")
 	healthFile = os.Getenv("HEALTH_FILE")
 	// authorizedKeysFile = os.Getenv("AUTHORIZED_KEYS_FILE")
 	authorizedKeysFile = ""
@@ -191,6 +241,9 @@ func main() {
 	if logLevel == "" {
 		flag.StringVar(&logLevel, "log-level", "INFO", "Log level (DEBUG, INFO, WARN, ERROR, FATAL)")
 	}
+	if logFormat == "" {
+		flag.StringVar(&logFormat, "log-format", "text", "Log output format (text or json)")
+	}
 	if updownScript == "" {
 		flag.StringVar(&updownScript, "updown", "", "Path to updown script to be called when targets are added or removed")
 	}
@@ -215,6 +268,9 @@ func main() {
 	if dockerSocket == "" {
 		flag.StringVar(&dockerSocket, "docker-socket", "", "Path or address to Docker socket (typically unix:///var/run/docker.sock)")
 	}
+	if dockerContext == "" {
+		flag.StringVar(&dockerContext, "docker-context", "", "Docker CLI context to use when no Docker socket is set")
+	}
 	if pingIntervalStr == "" {
 		flag.StringVar(&pingIntervalStr, "ping-interval", "3s", "Interval for pinging the server (default 3s)")
 	}
@@ -268,6 +324,57 @@ func main() {
 	if dockerEnforceNetworkValidation == "" {
 		flag.StringVar(&dockerEnforceNetworkValidation, "docker-enforce-network-validation", "false", "Enforce validation of container on newt network (true or false)")
 	}
+	if dockerTLSCA == "" {
+		flag.StringVar(&dockerTLSCA, "docker-tls-ca", "", "Path to CA certificate for a TLS protected remote Docker daemon")
+	}
+	if dockerTLSCert == "" {
+		flag.StringVar(&dockerTLSCert, "docker-tls-cert", "", "Path to client certificate for a TLS protected remote Docker daemon")
+	}
+	if dockerTLSKey == "" {
+		flag.StringVar(&dockerTLSKey, "docker-tls-key", "", "Path to client key for a TLS protected remote Docker daemon")
+	}
+	if dockerLabelFilter == "" {
+		flag.StringVar(&dockerLabelFilter, "docker-label-filter", "", "Comma separated container labels (key or key=value) required for discovery")
+	}
+	if dockerExcludeLabels == "" {
+		flag.StringVar(&dockerExcludeLabels, "docker-exclude-labels", docker.DisableLabelSelector, "Comma separated container labels (key or key=value) that opt a container out of discovery")
+	}
+	if dockerExcludeImages == "" {
+		flag.StringVar(&dockerExcludeImages, "docker-exclude-images", "fosrl/newt", "Comma separated image prefixes to never discover (Newt itself by default)")
+	}
+	if dockerPortLabels == "" {
+		flag.StringVar(&dockerPortLabels, "docker-port-labels", "", "Comma separated label keys (with * wildcards) that declare a container's port")
+	}
+	if dockerNetworks == "" {
+		flag.StringVar(&dockerNetworks, "docker-networks", "", "Comma separated Docker networks to restrict container discovery to")
+	}
+	if dockerNewtNetwork == "" {
+		flag.StringVar(&dockerNewtNetwork, "docker-newt-network", "", "Only discover containers on this Docker network Newt is attached to, ignoring Newt's other networks")
+	}
+	if dockerMinAPIVersion == "" {
+		flag.StringVar(&dockerMinAPIVersion, "docker-min-api-version", "", "Minimum Docker API version (e.g. 1.44), older daemons are rejected")
+	}
+	if dockerNames == "" {
+		flag.StringVar(&dockerNames, "docker-names", "", "Comma separated container name patterns (e.g. web-*) to restrict container discovery to")
+	}
+	if dockerEnvPrefix == "" {
+		flag.StringVar(&dockerEnvPrefix, "docker-env-prefix", "", "Send container environment variables starting with this prefix (e.g. NEWT_) to Pangolin")
+	}
+	if dockerLabelKeys == "" {
+		flag.StringVar(&dockerLabelKeys, "docker-label-keys", "", "Comma separated label keys (with * wildcards, e.g. traefik.*) of the container labels sent to Pangolin, all labels are sent when empty")
+	}
+	if dockerExcludePorts == "" {
+		flag.StringVar(&dockerExcludePorts, "docker-exclude-ports", "", "Comma separated ports or port ranges (e.g. 9000,9100-9110) that are never advertised as targets")
+	}
+	if dockerAddressMode == "" {
+		flag.StringVar(&dockerAddressMode, "docker-address-mode", "auto", "Send container IP addresses or hostnames to Pangolin (auto, ip or hostname)")
+	}
+	if dockerPreferUserNetworks == "" {
+		flag.StringVar(&dockerPreferUserNetworks, "docker-prefer-user-networks", "false", "Take container IP addresses from user defined, non-internal networks first (true or false)")
+	}
+	if dockerAddressFamily == "" {
+		flag.StringVar(&dockerAddressFamily, "docker-address-family", "ipv4", "IP address family used when container IP addresses are sent to Pangolin (ipv4, ipv6 or dualstack)")
+	}
 	if healthFile == "" {
 		flag.StringVar(&healthFile, "health-file", "", "Path to health file (if unset, health file won't be written)")
 	}
@@ -285,6 +392,8 @@ func main() {
 	logger.Init()
 	loggerLevel := parseLogLevel(logLevel)
 	logger.GetLogger().SetLevel(parseLogLevel(logLevel))
+	logger.SetFormat(logger.ParseFormat(logFormat))
+	setupLogLevelToggle(loggerLevel)
 
 	newtVersion := "version_replaceme"
 	if *version {
@@ -311,6 +420,30 @@ func main() {
 		dockerEnforceNetworkValidationBool = false
 	}
 
+	dockerPreferUserNetworksBool, err = strconv.ParseBool(dockerPreferUserNetworks)
+	if err != nil {
+		logger.Info("Docker prefer user networks cannot be parsed. Defaulting to 'false'")
+		dockerPreferUserNetworksBool = false
+	}
+
+	// parse which addresses to send for discovered containers
+	dockerAddressModeValue, err = docker.ParseAddressMode(dockerAddressMode)
+	if err != nil {
+		logger.Info("Docker address mode cannot be parsed. Defaulting to 'auto': %v", err)
+	}
+	dockerAddressFamilyValue, err = docker.ParseAddressFamily(dockerAddressFamily)
+	if err != nil {
+		logger.Info("Docker address family cannot be parsed. Defaulting to 'ipv4': %v", err)
+	}
+
+	// Use the endpoint of the docker CLI context when no socket is set
+	if dockerSocket == "" && dockerContext != "" {
+		dockerSocket, err = docker.ContextHost(dockerContext)
+		if err != nil {
+			logger.Warn("Failed to resolve Docker context %s, using %s: %v", dockerContext, dockerSocket, err)
+		}
+	}
+
 	// Add TLS configuration validation
 	if err := validateTLSConfig(); err != nil {
 		logger.Fatal("TLS configuration error: %v", err)
@@ -396,6 +529,57 @@ func main() {
 		logger.Debug("Up Down Script: %v", updownScript)
 	}
 
+	// Cancelled on shutdown so in-flight Docker requests stop promptly
+	dockerCtx, dockerCancel := context.WithCancel(context.Background())
+	defer dockerCancel()
+
+	// Create a single Docker client that is reused for every socket request
+	if dockerSocket != "" {
+		dockerConfig = docker.Config{
+			SocketPath:               dockerSocket,
+			MinAPIVersion:            dockerMinAPIVersion,
+			EnforceNetworkValidation: dockerEnforceNetworkValidationBool,
+			LabelSelectors:           splitList(dockerLabelFilter),
+			ExcludeLabelSelectors:    splitList(dockerExcludeLabels),
+			ExcludeImages:            splitList(dockerExcludeImages),
+			PortLabels:               splitList(dockerPortLabels),
+			Networks:                 splitList(dockerNetworks),
+			Names:                    splitList(dockerNames),
+			NewtNetwork:              dockerNewtNetwork,
+			AddressMode:              dockerAddressModeValue,
+			AddressFamily:            dockerAddressFamilyValue,
+			PreferUserNetworks:       dockerPreferUserNetworksBool,
+			EnvPrefix:                dockerEnvPrefix,
+			LabelKeys:                splitList(dockerLabelKeys),
+		}
+		dockerConfig.ExcludePorts, err = docker.ParsePortRanges(dockerExcludePorts)
+		if err != nil {
+			logger.Warn("Docker exclude ports cannot be parsed, no ports are excluded: %v", err)
+		}
+		if dockerTLSCA != "" || dockerTLSCert != "" || dockerTLSKey != "" {
+			dockerConfig.TLS = &docker.TLSConfig{
+				CAFile:   dockerTLSCA,
+				CertFile: dockerTLSCert,
+				KeyFile:  dockerTLSKey,
+			}
+		}
+		// DOCKER_SOCKET may list several daemons, e.g. a rootless and a rootful one
+		dockerClients, err = docker.NewClientsFromConfig(dockerConfig)
+		if err != nil {
+			logger.Error("Failed to create Docker client: %v", err)
+		}
+		for _, dockerClient := range dockerClients {
+			defer dockerClient.Close()
+
+			// Log the engine version once, without delaying start up on slow daemons
+			go func(dockerClient *docker.Client) {
+				if _, err := dockerClient.ServerVersion(dockerCtx); err != nil {
+					logger.Debug("Failed to get Docker engine version of %s: %v", dockerClient.SocketPath(), err)
+				}
+			}(dockerClient)
+		}
+	}
+
 	// Create TUN device and network stack
 	var tun tun.Device
 	var tnet *netstack.Net
@@ -915,8 +1099,8 @@ persistent_keepalive_interval=5`, fixKey(privateKey.String()), fixKey(wgData.Pub
 	client.RegisterHandler("newt/socket/check", func(msg websocket.WSMessage) {
 		logger.Debug("Received Docker socket check request")
 
-		if dockerSocket == "" {
-			logger.Debug("Docker socket path is not set")
+		if dockerSocket == "" || len(dockerClients) == 0 {
+			logger.Debug("Docker socket path is not set or the Docker client could not be created")
 			err := client.SendMessage("newt/socket/status", map[string]interface{}{
 				"available":  false,
 				"socketPath": dockerSocket,
@@ -927,8 +1111,16 @@ persistent_keepalive_interval=5`, fixKey(privateKey.String()), fixKey(wgData.Pub
 			return
 		}
 
-		// Check if Docker socket is available
-		isAvailable := docker.CheckSocket(dockerSocket)
+		// Check if any Docker socket is available
+		isAvailable := false
+		for _, dockerClient := range dockerClients {
+			available, err := dockerClient.CheckSocketWithError(dockerCtx)
+			if err != nil {
+				logger.Warn("Docker socket %s is not available: %v", dockerClient.SocketPath(), err)
+				continue
+			}
+			isAvailable = isAvailable || available
+		}
 
 		// Send response back to server
 		err := client.SendMessage("newt/socket/status", map[string]interface{}{
@@ -946,14 +1138,16 @@ persistent_keepalive_interval=5`, fixKey(privateKey.String()), fixKey(wgData.Pub
 	client.RegisterHandler("newt/socket/fetch", func(msg websocket.WSMessage) {
 		logger.Debug("Received Docker container fetch request")
 
-		if dockerSocket == "" {
-			logger.Debug("Docker socket path is not set")
+		if dockerSocket == "" || len(dockerClients) == 0 {
+			logger.Debug("Docker socket path is not set or the Docker client could not be created")
 			return
 		}
 
 		// List Docker containers
-		containers, err := docker.ListContainers(dockerSocket, dockerEnforceNetworkValidationBool)
-		if err != nil {
+		containers, err := docker.ListContainersWithConfig(dockerCtx, dockerClients, dockerConfig)
+		if errors.Is(err, docker.ErrPartialResults) {
+			logger.Warn("Some Docker containers were listed without inspect details: %v", err)
+		} else if err != nil {
 			logger.Error("Failed to list Docker containers: %v", err)
 			return
 		}
@@ -1235,6 +1429,9 @@ persistent_keepalive_interval=5`, fixKey(privateKey.String()), fixKey(wgData.Pub
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 	<-sigCh
 
+	// Stop any in-flight Docker requests
+	dockerCancel()
+
 	// Close clients first (including WGTester)
 	closeClients()
 
@@ -1301,3 +1498,11 @@ func validateTLSConfig() error {
 
 	return nil
 }
+
+// splitList splits a comma separated option value, an empty value yields nil
+func splitList(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
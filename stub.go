@@ -3,6 +3,7 @@
 package main
 
 import (
+	"github.com/fosrl/newt/logger"
 	"github.com/fosrl/newt/proxy"
 	"github.com/fosrl/newt/websocket"
 )
@@ -30,3 +31,8 @@ func clientsAddProxyTargetNative(pm *proxy.ProxyManager, tunnelIp string) {
 	// No-op for non-Linux systems
 	return
 }
+
+func setupLogLevelToggle(configured logger.LogLevel) {
+	// No-op for non-Linux systems
+	return
+}
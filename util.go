@@ -353,20 +353,11 @@ func startPingCheck(tnet *netstack.Net, serverIP string, client *websocket.Clien
 }
 
 func parseLogLevel(level string) logger.LogLevel {
-	switch strings.ToUpper(level) {
-	case "DEBUG":
-		return logger.DEBUG
-	case "INFO":
-		return logger.INFO
-	case "WARN":
-		return logger.WARN
-	case "ERROR":
-		return logger.ERROR
-	case "FATAL":
-		return logger.FATAL
-	default:
+	parsed, err := logger.ParseLevel(level)
+	if err != nil {
 		return logger.INFO // default to INFO if invalid level provided
 	}
+	return parsed
 }
 
 func mapToWireGuardLogLevel(level logger.LogLevel) int {